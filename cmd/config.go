@@ -1,11 +1,19 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gcli/internal/client"
 	"gcli/internal/config"
+	"gcli/internal/keyring"
+	"gcli/internal/output"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var configCmd = &cobra.Command{
@@ -22,14 +30,85 @@ var addCmd = &cobra.Command{
 		url, _ := cmd.Flags().GetString("url")
 		user, _ := cmd.Flags().GetString("user")
 		pass, _ := cmd.Flags().GetString("pass")
-		if name == "" || url == "" || user == "" || pass == "" {
-			return fmt.Errorf("all flags --name, --url, --user, --pass are required")
+		token, _ := cmd.Flags().GetString("token")
+		credCmd, _ := cmd.Flags().GetString("credential-command")
+		useKeyring, _ := cmd.Flags().GetBool("keyring")
+		headerFlags, _ := cmd.Flags().GetStringArray("header")
+		headers, err := parseHeaders(headerFlags)
+		if err != nil {
+			return err
+		}
+		if name == "" || url == "" {
+			return fmt.Errorf("flags --name and --url are required")
+		}
+		if token != "" && (user != "" || pass != "") {
+			return fmt.Errorf("--token cannot be combined with --user or --pass")
+		}
+		if credCmd != "" {
+			if pass != "" || token != "" {
+				return fmt.Errorf("--credential-command provides the secret; do not pass --pass or --token")
+			}
+			if useKeyring {
+				return fmt.Errorf("--credential-command cannot be combined with --keyring")
+			}
+		} else if token == "" && (user == "" || pass == "") && len(headers) == 0 {
+			// Without credentials only an auth proxy header can identify us.
+			return fmt.Errorf("either --token or both --user and --pass are required")
+		}
+		caFile, _ := cmd.Flags().GetString("ca-file")
+		clientCert, _ := cmd.Flags().GetString("client-cert")
+		clientKey, _ := cmd.Flags().GetString("client-key")
+		insecure, _ := cmd.Flags().GetBool("insecure")
+		if (clientCert == "") != (clientKey == "") {
+			return fmt.Errorf("--client-cert and --client-key must be used together")
+		}
+		proxy, _ := cmd.Flags().GetString("proxy")
+		timeout, _ := cmd.Flags().GetString("timeout")
+		if timeout != "" {
+			if _, err := time.ParseDuration(timeout); err != nil {
+				return fmt.Errorf("invalid --timeout: %w", err)
+			}
+		}
+		var retries *int
+		if cmd.Flags().Changed("retries") {
+			n, _ := cmd.Flags().GetInt("retries")
+			if n < 0 {
+				return fmt.Errorf("--retries must not be negative")
+			}
+			retries = &n
+		}
+		rateLimit, _ := cmd.Flags().GetFloat64("rate-limit")
+		if rateLimit < 0 {
+			return fmt.Errorf("--rate-limit must not be negative")
 		}
 		profile := config.Profile{
-			Name: name,
-			URL:  url,
-			User: user,
-			Pass: pass,
+			Name:               name,
+			URL:                url,
+			User:               user,
+			Pass:               pass,
+			Token:              token,
+			CredentialCommand:  credCmd,
+			CAFile:             absPath(caFile),
+			ClientCert:         absPath(clientCert),
+			ClientKey:          absPath(clientKey),
+			InsecureSkipVerify: insecure,
+			Proxy:              proxy,
+			Timeout:            timeout,
+			Headers:            headers,
+			Retries:            retries,
+			RateLimit:          rateLimit,
+		}
+		if useKeyring {
+			secret := pass
+			if token != "" {
+				secret = token
+			}
+			if err := keyring.Default.Set(name, secret); err != nil {
+				return fmt.Errorf("failed to store secret in keyring: %w", err)
+			}
+			profile.Pass = ""
+			profile.Token = ""
+			profile.Keyring = true
 		}
 		return config.SaveProfile(profile)
 	},
@@ -40,13 +119,49 @@ var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List saved Grafana configurations",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		printer, err := newPrinter(cmd, output.Table)
+		if err != nil {
+			return err
+		}
 		profiles, err := config.LoadAll()
 		if err != nil {
 			return err
 		}
-		b, _ := json.MarshalIndent(profiles, "", "  ")
-		fmt.Println(string(b))
-		return nil
+		active, err := config.GetActiveName()
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		list := make([]config.Profile, 0, len(names))
+		table := &output.TableData{Columns: []output.Column{
+			{Header: "Active"},
+			{Header: "Name"},
+			{Header: "URL"},
+			{Header: "Auth"},
+			{Header: "Org"},
+			{Header: "User", Wide: true},
+			{Header: "Proxy", Wide: true},
+		}}
+		for _, name := range names {
+			p := profiles[name]
+			list = append(list, p.Redacted())
+			marker := ""
+			if name == active {
+				marker = "*"
+			}
+			org := p.OrgID
+			if org == "" {
+				org = "-"
+			}
+			table.AddRow(marker, name, p.URL, authMethod(p), org, p.User, p.Proxy)
+		}
+		return printer.Print(list, table)
 	},
 }
 
@@ -61,18 +176,309 @@ var useCmd = &cobra.Command{
 	},
 }
 
+// rmCmd deletes a profile.
+var rmCmd = &cobra.Command{
+	Use:   "rm [profile-name]",
+	Short: "Delete a configuration profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.DeleteProfile(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Profile deleted: %s\n", args[0])
+		return nil
+	},
+}
+
+// renameCmd renames a profile.
+var renameCmd = &cobra.Command{
+	Use:   "rename [old-name] [new-name]",
+	Short: "Rename a configuration profile",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.RenameProfile(args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Profile renamed: %s -> %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+// showCmd prints a profile with its secrets masked.
+var showCmd = &cobra.Command{
+	Use:   "show [profile-name]",
+	Short: "Show a configuration profile (defaults to the active one)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer, err := newPrinter(cmd, output.YAML)
+		if err != nil {
+			return err
+		}
+		name, err := profileNameArg(args)
+		if err != nil {
+			return err
+		}
+		p, err := config.GetProfile(name)
+		if err != nil {
+			return err
+		}
+		return printer.Print(p.Redacted(), nil)
+	},
+}
+
+// editCmd opens a profile in $EDITOR.
+var editCmd = &cobra.Command{
+	Use:   "edit [profile-name]",
+	Short: "Edit a configuration profile interactively",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := profileNameArg(args)
+		if err != nil {
+			return err
+		}
+		p, err := config.GetProfile(name)
+		if err != nil {
+			return err
+		}
+		out, err := yaml.Marshal(p)
+		if err != nil {
+			return err
+		}
+		content := string(out)
+
+		var lastError string
+		for {
+			text := content
+			if lastError != "" {
+				text = "# ERROR: " + lastError + "\n# Fix the error below and save to retry.\n\n" + content
+			}
+			edited, err := editInEditor("gcli-profile-*.yaml", text)
+			if err != nil {
+				return err
+			}
+
+			// Drop the error banner from a previous round.
+			var cleanLines []string
+			for _, line := range strings.Split(string(edited), "\n") {
+				if strings.HasPrefix(line, "# ERROR: ") || strings.HasPrefix(line, "# Fix the error below") {
+					continue
+				}
+				cleanLines = append(cleanLines, line)
+			}
+			content = strings.TrimLeft(strings.Join(cleanLines, "\n"), "\n")
+			if strings.TrimSpace(content) == "" {
+				fmt.Println("No content, skipping update.")
+				return nil
+			}
+
+			var updated config.Profile
+			if err := yaml.Unmarshal([]byte(content), &updated); err != nil {
+				lastError = err.Error()
+				continue
+			}
+			if updated.Name != name {
+				lastError = fmt.Sprintf("the name cannot be changed here; use 'gcli config rename %s <new-name>'", name)
+				continue
+			}
+			if updated.URL == "" {
+				lastError = "url is required"
+				continue
+			}
+
+			if err := config.SaveProfile(updated); err != nil {
+				return err
+			}
+			fmt.Printf("Profile updated: %s\n", name)
+			return nil
+		}
+	},
+}
+
+// testCmd checks that a profile can reach and authenticate against Grafana.
+var testCmd = &cobra.Command{
+	Use:   "test [profile-name]",
+	Short: "Test the connection and credentials of a profile (defaults to the active one)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var p *config.Profile
+		var err error
+		if len(args) == 0 {
+			p, err = config.GetActive()
+			if err != nil {
+				return err
+			}
+			if p == nil {
+				return fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
+			}
+		} else {
+			p, err = config.GetProfile(args[0])
+			if err != nil {
+				return err
+			}
+			if err := config.ResolveCredentials(p); err != nil {
+				return err
+			}
+		}
+		c, err := client.New(p, p.OrgID)
+		if err != nil {
+			return err
+		}
+
+		var health struct {
+			Database string `json:"database"`
+			Version  string `json:"version"`
+		}
+		if err := c.Get("/api/health", &health); err != nil {
+			return fmt.Errorf("health check failed: %w", err)
+		}
+
+		var user struct {
+			Login          string `json:"login"`
+			Email          string `json:"email"`
+			IsGrafanaAdmin bool   `json:"isGrafanaAdmin"`
+		}
+		if err := c.Get("/api/user", &user); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+
+		fmt.Printf("Profile:  %s\n", p.Name)
+		fmt.Printf("URL:      %s\n", p.URL)
+		fmt.Printf("Database: %s\n", health.Database)
+		fmt.Printf("Version:  %s\n", health.Version)
+		login := user.Login
+		if user.Email != "" && user.Email != user.Login {
+			login = fmt.Sprintf("%s (%s)", user.Login, user.Email)
+		}
+		if user.IsGrafanaAdmin {
+			login += " [server admin]"
+		}
+		fmt.Printf("User:     %s\n", login)
+		return nil
+	},
+}
+
+// parseHeaders turns "Name: value" flags into a header map.
+func parseHeaders(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, expected 'Name: value'", v)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// absPath makes a file path given on the command line independent of the
+// working directory gcli is later run from.
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// migrateCmd upgrades the config file to the current schema version.
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the config file to the current schema version",
+	Long: `Upgrade the config file to the current schema version.
+
+gcli migrates older config files automatically when it reads them, keeping
+the previous file as config.yaml.v<N>.bak. Use --dry-run to preview the
+changes first.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		plan, err := config.Migrate(dryRun)
+		if err != nil {
+			return err
+		}
+		if len(plan.Steps) == 0 {
+			fmt.Printf("Config is up to date (version %d).\n", plan.To)
+			return nil
+		}
+
+		fmt.Printf("Migrating %s from version %d to %d:\n", plan.Path, plan.From, plan.To)
+		for _, step := range plan.Steps {
+			fmt.Printf("  - %s\n", step)
+		}
+		if dryRun {
+			fmt.Printf("\nResulting config (not written, secrets masked):\n%s", plan.Result)
+			return nil
+		}
+		fmt.Printf("Backup of the previous file: %s\n", plan.Backup)
+		return nil
+	},
+}
+
+// authMethod describes how a profile authenticates without revealing secrets.
+func authMethod(p config.Profile) string {
+	method := "basic"
+	if p.Token != "" || (p.User == "" && (p.Keyring || p.CredentialCommand != "")) {
+		method = "token"
+	}
+	switch {
+	case p.Keyring:
+		method += " (keyring)"
+	case p.CredentialCommand != "":
+		method += " (command)"
+	}
+	return method
+}
+
+// profileNameArg returns the profile named in args, or the active profile.
+func profileNameArg(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	name, err := config.GetActiveName()
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("no active profile set; pass a profile name or use 'gcli config use <name>' first")
+	}
+	return name, nil
+}
+
 func init() {
 	configCmd.AddCommand(addCmd)
 	configCmd.AddCommand(listCmd)
 	configCmd.AddCommand(useCmd)
+	configCmd.AddCommand(rmCmd)
+	configCmd.AddCommand(renameCmd)
+	configCmd.AddCommand(showCmd)
+	configCmd.AddCommand(editCmd)
+	configCmd.AddCommand(testCmd)
+	configCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().Bool("dry-run", false, "Show the changes without writing the config file")
 
 	// Flags for `add`.
 	addCmd.Flags().String("name", "", "Profile name")
 	addCmd.Flags().String("url", "", "Grafana base URL")
 	addCmd.Flags().String("user", "", "Basic auth username")
 	addCmd.Flags().String("pass", "", "Basic auth password")
+	addCmd.Flags().String("token", "", "Service account token or API key (used instead of --user/--pass)")
+	addCmd.Flags().String("credential-command", "", "Shell command that prints the password or token")
+	addCmd.Flags().Bool("keyring", false, "Store the password or token in the OS keyring instead of the config file")
+	addCmd.Flags().String("ca-file", "", "PEM file with additional CA certificates to trust")
+	addCmd.Flags().String("client-cert", "", "PEM client certificate for mutual TLS")
+	addCmd.Flags().String("client-key", "", "PEM private key for --client-cert")
+	addCmd.Flags().Bool("insecure", false, "Skip TLS certificate verification")
+	addCmd.Flags().String("proxy", "", "HTTP, HTTPS or SOCKS5 proxy URL (e.g. socks5://localhost:1080)")
+	addCmd.Flags().String("timeout", "", "Request timeout, e.g. 10s (default 30s)")
+	addCmd.Flags().Int("retries", client.DefaultRetries, "Retries of idempotent requests after 429, 502, 503, 504 or network errors (0 disables)")
+	addCmd.Flags().Float64("rate-limit", 0, "Maximum requests per second (0 for no limit)")
+	addCmd.Flags().StringArray("header", nil, "Extra header sent with every request as 'Name: value' (repeatable)")
 	addCmd.MarkFlagRequired("name")
 	addCmd.MarkFlagRequired("url")
-	addCmd.MarkFlagRequired("user")
-	addCmd.MarkFlagRequired("pass")
 }
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"gcli/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigCommands(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/health":
+			fmt.Fprintln(w, `{"database":"ok","version":"10.4.1"}`)
+		case "/api/user":
+			fmt.Fprintln(w, `{"login":"admin","email":"admin@localhost"}`)
+		}
+	}))
+	defer ts.Close()
+
+	tmpDir, _ := os.MkdirTemp("", "gcli-config-cmd-test-*")
+	defer os.RemoveAll(tmpDir)
+	tmpCfg := filepath.Join(tmpDir, "config.yaml")
+	os.Setenv("GCLI_CONFIG_PATH", tmpCfg)
+	defer os.Unsetenv("GCLI_CONFIG_PATH")
+
+	config.SaveProfile(config.Profile{Name: "test", URL: ts.URL, User: "admin", Pass: "supersecret"})
+	config.SetActive("test")
+
+	out, err := runCommand("config", "show")
+	if err != nil {
+		t.Fatalf("config show failed: %v", err)
+	}
+	if bytes.Contains([]byte(out), []byte("supersecret")) {
+		t.Errorf("config show leaked the password: %s", out)
+	}
+
+	out, err = runCommand("config", "list")
+	if err != nil {
+		t.Fatalf("config list failed: %v", err)
+	}
+	if bytes.Contains([]byte(out), []byte("supersecret")) {
+		t.Errorf("config list leaked the password: %s", out)
+	}
+
+	out, err = runCommand("config", "test")
+	if err != nil {
+		t.Fatalf("config test failed: %v", err)
+	}
+	if !bytes.Contains([]byte(out), []byte("10.4.1")) {
+		t.Errorf("expected Grafana version in output, got %s", out)
+	}
+
+	if _, err := runCommand("config", "rename", "test", "renamed"); err != nil {
+		t.Fatalf("config rename failed: %v", err)
+	}
+	if name, _ := config.GetActiveName(); name != "renamed" {
+		t.Errorf("expected active profile to follow rename, got %q", name)
+	}
+
+	if _, err := runCommand("config", "rm", "renamed"); err != nil {
+		t.Fatalf("config rm failed: %v", err)
+	}
+	profiles, _ := config.LoadAll()
+	if len(profiles) != 0 {
+		t.Errorf("expected no profiles after rm, got %v", profiles)
+	}
+}
@@ -1,48 +1,532 @@
 package cmd
 
 import (
-	"bytes"
-	"fmt"
-	"gcli/internal/config"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
-	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"gcli/internal/client"
+	"gcli/internal/config"
+	"gcli/internal/dashboard"
+	"gcli/internal/grafanatest"
 )
 
 func TestDashboardCommands(t *testing.T) {
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/api/search" {
-			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprintln(w, `[{"uid":"abc", "title":"Test Dash", "type":"dash-db"}]`)
+	srv := useFakeGrafana(t)
+	srv.AddFolder(grafanatest.MainOrgID, "ops", "Operations")
+	srv.AddDashboard(grafanatest.MainOrgID, "ops", map[string]interface{}{"uid": "abc", "title": "Test Dash", "tags": []interface{}{"prod"}})
+	srv.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "home", "title": "Home"})
+
+	out, err := runCommand("dash", "list")
+	if err != nil {
+		t.Fatalf("dash list failed: %v", err)
+	}
+	for _, want := range []string{"Test Dash", "Operations", "prod", "Home", "General"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in dash list output, got:\n%s", want, out)
 		}
-	}))
-	defer ts.Close()
+	}
 
-	tmpDir, _ := os.MkdirTemp("", "gcli-dash-cmd-test-*")
-	defer os.RemoveAll(tmpDir)
-	tmpCfg := filepath.Join(tmpDir, "config.yaml")
-	os.Setenv("GCLI_CONFIG_PATH", tmpCfg)
-	defer os.Unsetenv("GCLI_CONFIG_PATH")
+	out, err = runCommand("dash", "read", "abc")
+	if err != nil {
+		t.Fatalf("dash read failed: %v", err)
+	}
+	if !strings.Contains(out, `"title": "Test Dash"`) {
+		t.Errorf("expected the dashboard model, got:\n%s", out)
+	}
 
-	config.SaveProfile(config.Profile{Name: "test", URL: ts.URL, User: "a", Pass: "a"})
-	config.SetActive("test")
+	if _, err := runCommand("dash", "rm", "abc"); err != nil {
+		t.Fatalf("dash rm failed: %v", err)
+	}
+	if _, ok := srv.Dashboard(grafanatest.MainOrgID, "abc"); ok {
+		t.Error("expected dashboard abc to be deleted")
+	}
+	if _, err := runCommand("dash", "read", "abc"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("expected not found reading a deleted dashboard, got %v", err)
+	}
+}
 
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+func TestDashboardListReplay(t *testing.T) {
+	replayer, err := grafanatest.LoadReplayer(filepath.Join("testdata", "dash_list.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetTransport(func(http.RoundTripper) http.RoundTripper { return replayer })
+	defer client.SetTransport(nil)
+
+	useFakeGrafana(t)
+	config.SaveProfile(config.Profile{Name: "recorded", URL: "http://grafana.invalid", Token: "t"})
+	config.SetActive("recorded")
+
+	out, err := runCommand("dash", "list", "--sort-by", "title")
+	if err != nil {
+		t.Fatalf("dash list failed: %v", err)
+	}
+	for _, want := range []string{"Node Exporter", "Kubernetes / Pods", "Infrastructure"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+	if unused := replayer.Unused(); len(unused) > 0 {
+		t.Errorf("expected every recorded request to be made, %d left", len(unused))
+	}
+}
+
+func TestDashboardPullPush(t *testing.T) {
+	srv := useFakeGrafana(t)
+	srv.AddFolder(grafanatest.MainOrgID, "ops", "Operations")
+	srv.AddDashboard(grafanatest.MainOrgID, "ops", map[string]interface{}{"uid": "cpu", "title": "CPU / Load", "panels": []interface{}{}})
+	srv.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "home", "title": "Home"})
+	dir := filepath.Join(t.TempDir(), "dashboards")
+
+	if _, err := runCommand("dash", "pull", "--dir", dir); err != nil {
+		t.Fatalf("dash pull failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "operations", "cpu-load.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"panels\": [],\n  \"title\": \"CPU / Load\",\n  \"uid\": \"cpu\"\n}\n"; string(data) != want {
+		t.Errorf("unexpected dashboard file:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "home.json")); err != nil {
+		t.Errorf("expected the General dashboard at the top level: %v", err)
+	}
+
+	// A second pull and an unchanged push change nothing.
+	out, _ := runCommand("dash", "pull", "--dir", dir)
+	if !strings.Contains(out, "(0 changed)") {
+		t.Errorf("expected nothing to change on a second pull, got:\n%s", out)
+	}
+	out, err = runCommand("dash", "push", "--dir", dir)
+	if err != nil || !strings.Contains(out, "0 created, 0 updated, 2 unchanged") {
+		t.Errorf("expected an unchanged push, got:\n%s (%v)", out, err)
+	}
+
+	// Edit one dashboard and add one in a new folder.
+	os.WriteFile(filepath.Join(dir, "home.json"), []byte(`{"uid":"home","title":"Home v2"}`), 0644)
+	os.MkdirAll(filepath.Join(dir, "team-a"), 0755)
+	os.WriteFile(filepath.Join(dir, "team-a", "new.json"), []byte(`{"uid":"new","title":"New"}`), 0644)
+	out, err = runCommand("dash", "push", "--dir", dir, "--message", "from git")
+	if err != nil || !strings.Contains(out, "1 created, 1 updated, 1 unchanged") {
+		t.Fatalf("expected one create and one update, got:\n%s (%v)", out, err)
+	}
+	if model, _ := srv.Dashboard(grafanatest.MainOrgID, "home"); model["title"] != "Home v2" {
+		t.Errorf("expected home to be updated, got %v", model)
+	}
+	folderUID, _ := srv.DashboardFolder(grafanatest.MainOrgID, "new")
+	out, _ = runCommand("request", "GET", "/api/folders/"+folderUID)
+	if !strings.Contains(out, `"title": "team-a"`) {
+		t.Errorf("expected the new dashboard in a created team-a folder, got:\n%s", out)
+	}
+
+	// A dry run names the folders it would create as the target.
+	os.MkdirAll(filepath.Join(dir, "team-b", "nested"), 0755)
+	os.WriteFile(filepath.Join(dir, "team-b", "nested", "b.json"), []byte(`{"uid":"b","title":"B"}`), 0644)
+	out, err = runCommand("dash", "push", "--dir", dir, "--dry-run")
+	if err != nil {
+		t.Fatalf("dash push --dry-run failed: %v", err)
+	}
+	for _, want := range []string{"would create folder team-b", "would create folder nested", `"folderUid": "(new folder nested)"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the dry run, got:\n%s", want, out)
+		}
+	}
+	os.RemoveAll(filepath.Join(dir, "team-b"))
+
+	// Pulling a renamed dashboard replaces its old file.
+	srv.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "home", "title": "Start"})
+	runCommand("dash", "pull", "--dir", dir)
+	if _, err := os.Stat(filepath.Join(dir, "home.json")); !os.IsNotExist(err) {
+		t.Errorf("expected the old file of a renamed dashboard to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "start.json")); err != nil {
+		t.Errorf("expected the renamed dashboard file: %v", err)
+	}
+
+	// Dashboards swapping titles take over each other's files.
+	srv.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "home", "title": "New"})
+	srv.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "other", "title": "Start"})
+	runCommand("dash", "pull", "--dir", dir)
+	srv.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "home", "title": "Start"})
+	srv.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "other", "title": "New"})
+	if _, err := runCommand("dash", "pull", "--dir", dir); err != nil {
+		t.Fatalf("dash pull failed: %v", err)
+	}
+	for name, uid := range map[string]string{"start.json": "home", "new.json": "other"} {
+		var model map[string]interface{}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			err = json.Unmarshal(data, &model)
+		}
+		if err != nil || model["uid"] != uid {
+			t.Errorf("expected %s to hold dashboard %s, got %v (%v)", name, uid, model["uid"], err)
+		}
+	}
+}
+
+func TestDashboardPullFolderNames(t *testing.T) {
+	srv := useFakeGrafana(t)
+	srv.AddFolder(grafanatest.MainOrgID, "ops", "Ops")
+	srv.AddFolder(grafanatest.MainOrgID, "ops2", "ops!")
+	srv.AddDashboard(grafanatest.MainOrgID, "ops", map[string]interface{}{"uid": "a", "title": "A"})
+	srv.AddDashboard(grafanatest.MainOrgID, "ops2", map[string]interface{}{"uid": "b", "title": "B"})
+	dir := t.TempDir()
+
+	// Both titles make the file name "ops"; the folders keep their own
+	// directories.
+	if _, err := runCommand("dash", "pull", "--dir", dir); err != nil {
+		t.Fatalf("dash pull failed: %v", err)
+	}
+	files, err := dashboard.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	folders := map[string]string{}
+	for _, f := range files {
+		if len(f.Folders) == 1 {
+			folders[f.UID()] = f.Folders[0].UID
+		}
+	}
+	if folders["a"] != "ops" || folders["b"] != "ops2" {
+		t.Errorf("expected each dashboard under its own folder, got %v", folders)
+	}
+
+	out, err := runCommand("dash", "push", "--dir", dir)
+	if err != nil || !strings.Contains(out, "0 created, 0 updated, 2 unchanged") {
+		t.Errorf("expected an unchanged push, got:\n%s (%v)", out, err)
+	}
+	for uid, folder := range map[string]string{"a": "ops", "b": "ops2"} {
+		if got, _ := srv.DashboardFolder(grafanatest.MainOrgID, uid); got != folder {
+			t.Errorf("expected dashboard %s in folder %s, got %s", uid, folder, got)
+		}
+	}
+}
+
+func TestDashboardDiff(t *testing.T) {
+	srv := useFakeGrafana(t)
+	model := map[string]interface{}{"uid": "cpu", "title": "CPU", "panels": []interface{}{map[string]interface{}{"title": "Load", "type": "graph"}}}
+	srv.AddDashboard(grafanatest.MainOrgID, "", model)
+
+	staging := grafanatest.NewServer()
+	defer staging.Close()
+	config.SaveProfile(staging.Profile("staging"))
+	staging.AddDashboard(grafanatest.MainOrgID, "", model)
+
+	// Only id and version differ from a pulled file.
+	file := filepath.Join(t.TempDir(), "cpu.json")
+	os.WriteFile(file, []byte(`{"uid":"cpu","title":"CPU","id":99,"version":42,"panels":[{"title":"Load","type":"graph"}]}`), 0644)
+	out, err := runCommand("dash", "diff", "cpu", "--file", file)
+	if err != nil || !strings.Contains(out, "No differences.") {
+		t.Errorf("expected no differences, got:\n%s (%v)", out, err)
+	}
+	if _, err := runCommand("dash", "diff", "cpu", "--to-profile", "staging"); err != nil {
+		t.Errorf("expected no differences between profiles, got %v", err)
+	}
+
+	staging.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "cpu", "title": "CPU", "panels": []interface{}{map[string]interface{}{"title": "Load", "type": "timeseries"}}})
+	out, err = runCommand("dash", "diff", "cpu", "--to-profile", "staging")
+	if exitCode(err) != exitError {
+		t.Errorf("expected exit code %d for differences, got %d (%v)", exitError, exitCode(err), err)
+	}
+	if !strings.Contains(out, `~ panels[0].type  (panel "Load")`) || !strings.Contains(out, `+ "timeseries"`) {
+		t.Errorf("expected the changed panel type, got:\n%s", out)
+	}
+
+	if _, err := runCommand("dash", "diff", "cpu"); exitCode(err) != exitUsage {
+		t.Errorf("expected a usage error without --file or --to-profile, got %v", err)
+	}
+
+	// Failures must not look like drift.
+	for _, tt := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"dash", "diff", "cpu", "--file", filepath.Join(t.TempDir(), "missing.json")}, exitUsage},
+		{[]string{"dash", "diff", "cpu", "--to-profile", "typo"}, exitUsage},
+		{[]string{"dash", "diff", "missing", "--file", file}, exitNotFound},
+	} {
+		if _, err := runCommand(tt.args...); exitCode(err) != tt.code {
+			t.Errorf("%v: expected exit code %d, got %d (%v)", tt.args, tt.code, exitCode(err), err)
+		}
+	}
+}
 
-	rootCmd.SetArgs([]string{"dash", "list"})
-	rootCmd.Execute()
+func TestDashboardVersions(t *testing.T) {
+	srv := useFakeGrafana(t)
+	for _, title := range []string{"CPU", "CPU v2", "CPU v3"} {
+		srv.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "cpu", "title": title})
+	}
+
+	out, err := runCommand("dash", "versions", "cpu", "--columns", "version,author")
+	if err != nil {
+		t.Fatalf("dash versions failed: %v", err)
+	}
+	if !strings.Contains(out, "3 ") || !strings.Contains(out, "admin") {
+		t.Errorf("expected versions with their author, got:\n%s", out)
+	}
+	srv.Version = "11.0.0"
+	out, err = runCommand("dash", "versions", "cpu", "-o", "jsonpath={.items[*].version}")
+	if err != nil || out != "3 2 1" {
+		t.Errorf("expected versions newest first from the paged format, got %q (%v)", out, err)
+	}
+
+	out, err = runCommand("dash", "versions", "diff", "cpu", "1", "3")
+	if exitCode(err) != exitError || !strings.Contains(out, "~ title") || !strings.Contains(out, `+ "CPU v3"`) {
+		t.Errorf("expected the title change between versions, got:\n%s (%v)", out, err)
+	}
+
+	// Failed fetches exit 2 or higher, not 1 like differences.
+	if _, err := runCommand("dash", "versions", "diff", "cpu", "1", "9"); exitCode(err) != exitNotFound {
+		t.Errorf("expected exit code %d for a missing version, got %d (%v)", exitNotFound, exitCode(err), err)
+	}
+	client.SetTransport(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Status:     "500 Internal Server Error",
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(`{"message":"database is locked"}`)),
+				Request:    req,
+			}, nil
+		})
+	})
+	_, err = runCommand("dash", "versions", "diff", "cpu", "1", "3")
+	client.SetTransport(nil)
+	if exitCode(err) != exitUsage {
+		t.Errorf("expected exit code %d for a server error, got %d (%v)", exitUsage, exitCode(err), err)
+	}
+
+	out, err = runCommand("dash", "restore", "cpu", "--version", "1")
+	if err != nil || !strings.Contains(out, "saved as version 4") {
+		t.Fatalf("expected restore to save version 4, got:\n%s (%v)", out, err)
+	}
+	if model, _ := srv.Dashboard(grafanatest.MainOrgID, "cpu"); model["title"] != "CPU" {
+		t.Errorf("expected the title of version 1 after restoring, got %v", model["title"])
+	}
+	out, _ = runCommand("dash", "versions", "cpu", "--limit", "1", "-o", "wide")
+	if !strings.Contains(out, "Restored from version 1") {
+		t.Errorf("expected the restore in the history, got:\n%s", out)
+	}
+
+	if _, err := runCommand("dash", "restore", "cpu", "--version", "9"); exitCode(err) != exitNotFound {
+		t.Errorf("expected not found restoring a missing version, got %v", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestDashboardUpdateConflicts(t *testing.T) {
+	srv := useFakeGrafana(t)
+	srv.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "cpu", "title": "CPU", "refresh": "1m"})
+
+	// The editor appends " edited" to the title.
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	os.WriteFile(editor, []byte("#!/bin/sh\nsed -i 's/\"title\": \"\\(.*\\)\"/\"title\": \"\\1 edited\"/' \"$1\"\n"), 0755)
+	os.Setenv("EDITOR", editor)
+	defer os.Unsetenv("EDITOR")
+
+	// colleague, when set, saves the dashboard just before gcli does.
+	var colleague map[string]interface{}
+	client.SetTransport(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost && colleague != nil {
+				srv.AddDashboard(grafanatest.MainOrgID, "", colleague)
+				colleague = nil
+			}
+			return next.RoundTrip(req)
+		})
+	})
+	defer client.SetTransport(nil)
+
+	update := func(stdin string) (string, error) {
+		oldIn := os.Stdin
+		r, w, _ := os.Pipe()
+		w.WriteString(stdin)
+		w.Close()
+		os.Stdin = r
+		defer func() { os.Stdin = oldIn }()
+		return runCommand("dash", "update", "cpu", "--message", "tweak")
+	}
+	title := func() interface{} {
+		model, _ := srv.Dashboard(grafanatest.MainOrgID, "cpu")
+		return model["title"]
+	}
+
+	if out, err := update(""); err != nil {
+		t.Fatalf("dash update failed: %v\n%s", err, out)
+	}
+	out, _ := runCommand("dash", "versions", "cpu", "-o", "jsonpath={.items[0].message}")
+	if title() != "CPU edited" || out != "tweak" {
+		t.Errorf("expected the edit saved with its message, got title %v and message %q", title(), out)
+	}
+
+	// Aborting leaves the colleague's version in place.
+	colleague = map[string]interface{}{"uid": "cpu", "title": "Theirs", "refresh": "5m"}
+	out, err := update("a\n")
+	if exitCode(err) != exitConflict || !strings.Contains(out, "changed by someone else") {
+		t.Errorf("expected a conflict after aborting, got %v\n%s", err, out)
+	}
+	if title() != "Theirs" {
+		t.Errorf("expected their title to be kept, got %v", title())
+	}
+
+	// The three-way diff shows both sides, then forcing saves ours.
+	colleague = map[string]interface{}{"uid": "cpu", "title": "Theirs again", "refresh": "5m"}
+	out, err = update("d\nf\n")
+	if err != nil {
+		t.Fatalf("forced update failed: %v\n%s", err, out)
+	}
+	for _, want := range []string{"Your changes", "Their changes", `+ "Theirs again"`, "Changed on both sides:\n  title"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the three-way diff, got:\n%s", want, out)
+		}
+	}
+	if title() != "Theirs edited" {
+		t.Errorf("expected the forced edit, got %v", title())
+	}
+
+	// Re-fetching edits the current version instead.
+	colleague = map[string]interface{}{"uid": "cpu", "title": "Latest", "refresh": "5m"}
+	if out, err := update("r\n"); err != nil || !strings.Contains(out, "Your edit was saved to") {
+		t.Fatalf("re-fetch failed: %v\n%s", err, out)
+	}
+	if title() != "Latest edited" {
+		t.Errorf("expected the edit of the re-fetched version, got %v", title())
+	}
+}
+
+func TestDashboardCreateFromTemplate(t *testing.T) {
+	srv := useFakeGrafana(t)
+	srv.AddFolder(grafanatest.MainOrgID, "ops", "Operations")
+	srv.AddDatasource(grafanatest.MainOrgID, grafanatest.Datasource{UID: "p1", Name: "Prom1", Type: "prometheus"})
+	srv.AddDatasource(grafanatest.MainOrgID, grafanatest.Datasource{UID: "loki", Name: "Loki", Type: "loki"})
+
+	dir := t.TempDir()
+	template := filepath.Join(dir, "template.json")
+	os.WriteFile(template, []byte(`{
+  "__inputs": [
+    {"name": "DS_PROM", "label": "Prometheus", "type": "datasource", "pluginId": "prometheus"},
+    {"name": "VAR_ENV", "type": "constant", "value": "prod"}
+  ],
+  "__requires": [{"type": "datasource", "id": "prometheus"}],
+  "id": 12, "uid": "nodes", "title": "Nodes",
+  "panels": [{"title": "CPU", "datasource": {"uid": "${DS_PROM}"}, "targets": [{"expr": "up{env=\"${VAR_ENV}\"}"}]}]
+}`), 0644)
+
+	// Without a terminal there are no prompts.
+	oldIn := os.Stdin
+	r, w, _ := os.Pipe()
 	w.Close()
-	os.Stdout = old
+	os.Stdin = r
+	defer func() { os.Stdin = oldIn }()
+
+	panel := func(uid string) (string, string) {
+		model, _ := srv.Dashboard(grafanatest.MainOrgID, uid)
+		p, _ := model["panels"].([]interface{})
+		if len(p) == 0 {
+			return "", ""
+		}
+		m := p[0].(map[string]interface{})
+		ds := m["datasource"].(map[string]interface{})["uid"].(string)
+		expr := m["targets"].([]interface{})[0].(map[string]interface{})["expr"].(string)
+		return ds, expr
+	}
+
+	if _, err := runCommand("dash", "create", "--file", template); err == nil || !strings.Contains(err.Error(), "--map DS_PROM") {
+		t.Errorf("expected an error naming the unmapped input, got %v", err)
+	}
+
+	_, err := runCommand("dash", "create", "--file", template, "--auto-map", "--folder", "operations", "--title", "Nodes (prod)")
+	if err != nil {
+		t.Fatalf("dash create --auto-map failed: %v", err)
+	}
+	if ds, expr := panel("nodes"); ds != "p1" || expr != `up{env="prod"}` {
+		t.Errorf("expected datasource p1 and the default constant, got %s and %s", ds, expr)
+	}
+	if model, _ := srv.Dashboard(grafanatest.MainOrgID, "nodes"); model["title"] != "Nodes (prod)" {
+		t.Errorf("expected the --title, got %v", model["title"])
+	}
+	if folder, _ := srv.DashboardFolder(grafanatest.MainOrgID, "nodes"); folder != "ops" {
+		t.Errorf("expected the dashboard in folder ops, got %q", folder)
+	}
+
+	srv.AddDatasource(grafanatest.MainOrgID, grafanatest.Datasource{UID: "p2", Name: "Prom2", Type: "prometheus"})
+	if _, err := runCommand("dash", "create", "--file", template, "--auto-map", "--uid", "other"); err == nil || !strings.Contains(err.Error(), "Prom1, Prom2") {
+		t.Errorf("expected auto-map to fail with two candidates, got %v", err)
+	}
+
+	_, err = runCommand("dash", "create", "--file", template, "--map", "DS_PROM=Prom2", "--map", "VAR_ENV=staging", "--uid", "nodes-staging", "--title", "Nodes (staging)")
+	if err != nil {
+		t.Fatalf("dash create --map failed: %v", err)
+	}
+	if ds, expr := panel("nodes-staging"); ds != "p2" || expr != `up{env="staging"}` {
+		t.Errorf("expected datasource p2 and the mapped constant, got %s and %s", ds, expr)
+	}
+
+	if _, err := runCommand("dash", "create", "--file", template, "--map", "DS_PROM=Loki"); err == nil || !strings.Contains(err.Error(), "needs prometheus") {
+		t.Errorf("expected a type mismatch error, got %v", err)
+	}
+
+	mappingFile := filepath.Join(dir, "mapping.yaml")
+	os.WriteFile(mappingFile, []byte("DS_PROM: p2\n"), 0644)
+	if _, err := runCommand("dash", "create", "--file", template, "--mapping-file", mappingFile); exitCode(err) != exitConflict {
+		t.Errorf("expected a conflict creating an existing uid without --overwrite, got %v", err)
+	}
+	if _, err := runCommand("dash", "create", "--file", template, "--mapping-file", mappingFile, "--overwrite"); err != nil {
+		t.Fatalf("dash create --overwrite failed: %v", err)
+	}
+	if ds, _ := panel("nodes"); ds != "p2" {
+		t.Errorf("expected the overwritten dashboard to use p2, got %s", ds)
+	}
+}
+
+func TestDashboardCreatePrompts(t *testing.T) {
+	srv := useFakeGrafana(t)
+	defer func(f func() bool) { stdinIsTerminal = f }(stdinIsTerminal)
+	stdinIsTerminal = func() bool { return true }
 
-	var buf bytes.Buffer
-	io.Copy(&buf, r)
+	create := func(file, stdin string) string {
+		t.Helper()
+		oldIn := os.Stdin
+		r, w, _ := os.Pipe()
+		w.WriteString(stdin)
+		w.Close()
+		os.Stdin = r
+		defer func() { os.Stdin = oldIn }()
+		out, err := runCommand("dash", "create", "--file", file)
+		if err != nil {
+			t.Fatalf("dash create failed: %v", err)
+		}
+		return out
+	}
+	dir := t.TempDir()
 
-	if !bytes.Contains(buf.Bytes(), []byte("Test Dash")) {
-		t.Errorf("expected 'Test Dash' in output, got %s", buf.String())
+	// A file with a title and uid is created as it is, without questions.
+	complete := filepath.Join(dir, "complete.json")
+	os.WriteFile(complete, []byte(`{"uid":"cpu","title":"CPU"}`), 0644)
+	if out := create(complete, ""); strings.Contains(out, "Enter") {
+		t.Errorf("expected no prompts for a complete file, got:\n%s", out)
+	}
+	if model, _ := srv.Dashboard(grafanatest.MainOrgID, "cpu"); model["title"] != "CPU" {
+		t.Errorf("expected the file's title, got %v", model["title"])
+	}
+
+	// Missing values are asked for.
+	bare := filepath.Join(dir, "bare.json")
+	os.WriteFile(bare, []byte(`{"panels":[]}`), 0644)
+	out := create(bare, "Memory\nmem\n")
+	if !strings.Contains(out, "Enter title: ") || !strings.Contains(out, "Enter UID") {
+		t.Errorf("expected prompts for the title and UID, got:\n%s", out)
+	}
+	if model, _ := srv.Dashboard(grafanatest.MainOrgID, "mem"); model["title"] != "Memory" {
+		t.Errorf("expected the answered title and uid, got %v", model)
 	}
 }
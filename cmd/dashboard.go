@@ -4,18 +4,20 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 
-	"gcli/internal/config"
-	"gcli/internal/datasource"
+	"gcli/internal/client"
+	"gcli/internal/dashboard"
+	"gcli/internal/output"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var dashCmd = &cobra.Command{
@@ -28,71 +30,51 @@ var dashListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List dashboards for the active profile and organization",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		profile, err := config.GetActive()
+		printer, err := newPrinter(cmd, output.Table)
 		if err != nil {
 			return err
 		}
-		if profile == nil {
-			return fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
-		}
-
-		// Use the search API to list dashboards
-		url := fmt.Sprintf("%s/api/search?type=dash-db", profile.URL)
-		req, err := http.NewRequest(http.MethodGet, url, nil)
+		c, err := client.FromActive()
 		if err != nil {
 			return err
 		}
-		req.SetBasicAuth(profile.User, profile.Pass)
 
-		// Set Org ID header
-		activeOrg, _ := config.GetActiveOrg()
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
-		}
-
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("list failed: %s %s", resp.Status, string(body))
-		}
-
-		details, _ := cmd.Flags().GetBool("details")
-		if details {
-			pretty, _ := datasource.PrettyPrintJSON(body)
-			fmt.Printf("Status: %s\n%s\n", resp.Status, string(pretty))
-			return nil
+		// Use the search API to list dashboards, following its pages
+		limit, _ := cmd.Flags().GetInt("limit")
+		var body []byte
+		if err := c.GetAll("/api/search?type=dash-db", limit, &body); err != nil {
+			return fmt.Errorf("list failed: %w", err)
 		}
 
 		var items []struct {
 			UID         string   `json:"uid"`
 			Title       string   `json:"title"`
 			FolderTitle string   `json:"folderTitle"`
+			FolderUID   string   `json:"folderUid"`
+			URL         string   `json:"url"`
 			Tags        []string `json:"tags"`
 		}
 		if err := json.Unmarshal(body, &items); err != nil {
 			return fmt.Errorf("failed to parse response: %w", err)
 		}
 
-		fmt.Printf("%-40s %-30s %-20s %s\n", "UID", "Title", "Folder", "Tags")
-		fmt.Println("------------------------------------------------------------------------------------------------------------------------")
+		table := &output.TableData{Columns: []output.Column{
+			{Header: "UID"},
+			{Header: "Title"},
+			{Header: "Folder"},
+			{Header: "Tags"},
+			{Header: "Folder UID", Wide: true},
+			{Header: "URL", Wide: true},
+		}}
 		for _, item := range items {
-			tags := ""
-			if len(item.Tags) > 0 {
-				tags = fmt.Sprintf("%v", item.Tags)
-			}
 			folder := item.FolderTitle
 			if folder == "" {
 				folder = "General"
 			}
-			fmt.Printf("%-40s %-30s %-20s %s\n", item.UID, item.Title, folder, tags)
+			table.AddRow(item.UID, item.Title, folder, strings.Join(item.Tags, ","), item.FolderUID, item.URL)
 		}
 
-		return nil
+		return printer.Print(body, table)
 	},
 }
 
@@ -105,41 +87,22 @@ var dashReadCmd = &cobra.Command{
 		uid := args[0]
 		external, _ := cmd.Flags().GetBool("external")
 
-		profile, err := config.GetActive()
+		printer, err := newPrinter(cmd, output.JSON)
+		if err != nil {
+			return err
+		}
+		c, err := client.FromActive()
 		if err != nil {
 			return err
 		}
-
-		activeOrg, _ := config.GetActiveOrg()
 
 		if external {
 			// For external export, we use the export API
-			url := fmt.Sprintf("%s/api/dashboards/uid/%s", profile.URL, uid)
-			req, err := http.NewRequest(http.MethodGet, url, nil)
-			if err != nil {
-				return err
-			}
-			req.SetBasicAuth(profile.User, profile.Pass)
-			if activeOrg != "" {
-				req.Header.Set("X-Grafana-Org-Id", activeOrg)
-			}
-
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				return err
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode != http.StatusOK {
-				body, _ := io.ReadAll(resp.Body)
-				return fmt.Errorf("read failed: %s %s", resp.Status, string(body))
-			}
-
 			var dashData struct {
 				Dashboard json.RawMessage `json:"dashboard"`
 			}
-			if err := json.NewDecoder(resp.Body).Decode(&dashData); err != nil {
-				return err
+			if err := c.Get("/api/dashboards/uid/"+uid, &dashData); err != nil {
+				return fmt.Errorf("read failed: %w", err)
 			}
 
 			var dashObj map[string]interface{}
@@ -148,23 +111,14 @@ var dashReadCmd = &cobra.Command{
 			}
 
 			// 1. Fetch all datasources to map UIDs to names/types
-			dsURL := fmt.Sprintf("%s/api/datasources", profile.URL)
-			dsReq, _ := http.NewRequest(http.MethodGet, dsURL, nil)
-			dsReq.SetBasicAuth(profile.User, profile.Pass)
-			if activeOrg != "" {
-				dsReq.Header.Set("X-Grafana-Org-Id", activeOrg)
-			}
-			dsResp, err := http.DefaultClient.Do(dsReq)
-			if err != nil {
-				return fmt.Errorf("failed to fetch datasources for mapping: %w", err)
-			}
-			defer dsResp.Body.Close()
 			var allDS []struct {
 				UID  string `json:"uid"`
 				Name string `json:"name"`
 				Type string `json:"type"`
 			}
-			json.NewDecoder(dsResp.Body).Decode(&allDS)
+			if err := c.Get("/api/datasources", &allDS); err != nil {
+				return fmt.Errorf("failed to fetch datasources for mapping: %w", err)
+			}
 
 			dsMap := make(map[string]struct{ Name, Type string })
 			for _, ds := range allDS {
@@ -228,48 +182,25 @@ var dashReadCmd = &cobra.Command{
 				exportOutput[k] = v
 			}
 
-			pretty, _ := json.MarshalIndent(exportOutput, "", "  ")
-			fmt.Println(string(pretty))
-			return nil
+			return printer.Print(exportOutput, nil)
 		}
 
 		// Standard read
-		url := fmt.Sprintf("%s/api/dashboards/uid/%s", profile.URL, uid)
-		req, err := http.NewRequest(http.MethodGet, url, nil)
-		if err != nil {
-			return err
-		}
-		req.SetBasicAuth(profile.User, profile.Pass)
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
-		}
-
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("read failed: %s %s", resp.Status, string(body))
+		var body []byte
+		if err := c.Get("/api/dashboards/uid/"+uid, &body); err != nil {
+			return fmt.Errorf("read failed: %w", err)
 		}
 
 		// Typically we want just the dashboard metadata, not the whole wrapper
 		var wrapper struct {
 			Dashboard json.RawMessage `json:"dashboard"`
 		}
-		if err := json.Unmarshal(body, &wrapper); err != nil {
+		if err := json.Unmarshal(body, &wrapper); err != nil || len(wrapper.Dashboard) == 0 {
 			// Fallback to printing whole thing if we can't parse wrapper
-			pretty, _ := datasource.PrettyPrintJSON(body)
-			fmt.Println(string(pretty))
-			return nil
+			return printer.Print(body, nil)
 		}
 
-		pretty, _ := datasource.PrettyPrintJSON(wrapper.Dashboard)
-		fmt.Println(string(pretty))
-
-		return nil
+		return printer.Print(wrapper.Dashboard, nil)
 	},
 }
 
@@ -281,32 +212,13 @@ var dashRmCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		uid := args[0]
 
-		profile, err := config.GetActive()
-		if err != nil {
-			return err
-		}
-
-		activeOrg, _ := config.GetActiveOrg()
-
-		url := fmt.Sprintf("%s/api/dashboards/uid/%s", profile.URL, uid)
-		req, err := http.NewRequest(http.MethodDelete, url, nil)
-		if err != nil {
-			return err
-		}
-		req.SetBasicAuth(profile.User, profile.Pass)
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
-		}
-
-		resp, err := http.DefaultClient.Do(req)
+		c, err := client.FromActive()
 		if err != nil {
 			return err
 		}
-		defer resp.Body.Close()
 
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("delete failed: %s %s", resp.Status, string(body))
+		if err := c.Delete("/api/dashboards/uid/"+uid, nil); err != nil {
+			return fmt.Errorf("delete failed: %w", err)
 		}
 
 		fmt.Printf("Dashboard deleted: %s\n", uid)
@@ -318,76 +230,39 @@ var dashRmCmd = &cobra.Command{
 var dashUpdateCmd = &cobra.Command{
 	Use:   "update [UID]",
 	Short: "Update a dashboard interactively",
-	Args:  cobra.ExactArgs(1),
+	Long: `Update a dashboard in $EDITOR.
+
+The update is saved against the version that was fetched. If someone else
+saved the dashboard in the meantime, gcli offers to re-fetch it and edit the
+current version, to show a three-way diff of both changes, or to force the
+update over theirs.`,
+	Example: `  gcli dash update cpu --message "Raise the CPU alert threshold"`,
+	Args:    cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		uid := args[0]
-		profile, err := config.GetActive()
+		message, _ := cmd.Flags().GetString("message")
+		c, err := client.FromActive()
 		if err != nil {
 			return err
 		}
-		activeOrg, _ := config.GetActiveOrg()
 
 		// Fetch current dashboard
-		url := fmt.Sprintf("%s/api/dashboards/uid/%s", profile.URL, uid)
-		req, _ := http.NewRequest(http.MethodGet, url, nil)
-		req.SetBasicAuth(profile.User, profile.Pass)
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
-		}
-
-		resp, err := http.DefaultClient.Do(req)
+		base, err := fetchDashboard(c, uid)
 		if err != nil {
 			return err
 		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to fetch dashboard: %s", resp.Status)
-		}
-
-		var wrapper struct {
-			Dashboard json.RawMessage `json:"dashboard"`
-			Metadata  struct {
-				FolderUID string `json:"folderUid"`
-			} `json:"meta"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-			return err
-		}
-
-		pretty, _ := datasource.PrettyPrintJSON(wrapper.Dashboard)
-		content := string(pretty)
+		content, _ := dashboard.Marshal(base.Model)
+		text := string(content)
+		reader := bufio.NewReader(os.Stdin)
 
 		var lastError string
+	edit:
 		for {
-			tmpFile, err := os.CreateTemp("", "gcli-dash-*.json")
-			if err != nil {
-				return err
-			}
-			defer os.Remove(tmpFile.Name())
-
+			header := ""
 			if lastError != "" {
-				tmpFile.WriteString("// ERROR: " + lastError + "\n")
-				tmpFile.WriteString("// Fix the error below and save to retry.\n\n")
+				header = "// ERROR: " + lastError + "\n// Fix the error below and save to retry.\n\n"
 			}
-			tmpFile.WriteString(content)
-			tmpFile.Close()
-
-			editor := os.Getenv("EDITOR")
-			if editor == "" {
-				editor = "vi"
-			}
-
-			ecmd := exec.Command(editor, tmpFile.Name())
-			ecmd.Stdin = os.Stdin
-			ecmd.Stdout = os.Stdout
-			ecmd.Stderr = os.Stderr
-			if err := ecmd.Run(); err != nil {
-				return fmt.Errorf("editor failed: %w", err)
-			}
-
-			// Read back
-			updatedBytes, err := os.ReadFile(tmpFile.Name())
+			updatedBytes, err := editInEditor("gcli-dash-*.json", header+text)
 			if err != nil {
 				return err
 			}
@@ -412,58 +287,216 @@ var dashUpdateCmd = &cobra.Command{
 			var dashObj map[string]interface{}
 			if err := json.Unmarshal([]byte(cleanJSON), &dashObj); err != nil {
 				lastError = err.Error()
-				content = cleanJSON
+				text = cleanJSON
 				continue
 			}
 
-			// Prepare update payload
-			payload := map[string]interface{}{
-				"dashboard": dashObj,
-				"overwrite": true,
-			}
-			if wrapper.Metadata.FolderUID != "" {
-				payload["folderUid"] = wrapper.Metadata.FolderUID
-			}
+			overwrite := false
+			for {
+				ubody, err := saveDashboardUpdate(c, dashObj, base, message, overwrite)
+				if err == nil {
+					fmt.Printf("Dashboard updated successfully.\n%s\n", string(ubody))
+					return nil
+				}
+				var apiErr *client.APIError
+				if !errors.As(err, &apiErr) {
+					return err
+				}
+				if !isVersionMismatch(apiErr) {
+					lastError = fmt.Sprintf("%s: %s", apiErr.Status, string(apiErr.Body))
+					text = cleanJSON
+					continue edit
+				}
 
-			payloadBytes, _ := json.Marshal(payload)
-			updateURL := fmt.Sprintf("%s/api/dashboards/db", profile.URL)
-			ureq, _ := http.NewRequest(http.MethodPost, updateURL, bytes.NewReader(payloadBytes))
-			ureq.Header.Set("Content-Type", "application/json")
-			ureq.SetBasicAuth(profile.User, profile.Pass)
-			if activeOrg != "" {
-				ureq.Header.Set("X-Grafana-Org-Id", activeOrg)
+				latest, ferr := fetchDashboard(c, uid)
+				if ferr != nil {
+					return ferr
+				}
+				switch resolveVersionConflict(reader, uid, base, dashObj, latest) {
+				case conflictForce:
+					overwrite = true
+				case conflictRefetch:
+					saved, err := saveEdit(uid, cleanJSON)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Your edit was saved to %s.\n", saved)
+					base = latest
+					content, _ := dashboard.Marshal(base.Model)
+					text = string(content)
+					lastError = ""
+					continue edit
+				default:
+					return err
+				}
 			}
+		}
+	},
+}
 
-			uresp, err := http.DefaultClient.Do(ureq)
-			if err != nil {
-				return err
-			}
-			defer uresp.Body.Close()
+// dashboardState is a dashboard model with the metadata needed to save it.
+type dashboardState struct {
+	Model     map[string]interface{}
+	Version   int
+	FolderUID string
+	UpdatedBy string
+}
 
-			ubody, _ := io.ReadAll(uresp.Body)
-			if uresp.StatusCode != http.StatusOK {
-				lastError = fmt.Sprintf("%s: %s", uresp.Status, string(ubody))
-				content = cleanJSON
-				continue
-			}
+// fetchDashboard returns the dashboard with the given uid and its metadata.
+func fetchDashboard(c *client.Client, uid string) (*dashboardState, error) {
+	var wrapper struct {
+		Dashboard map[string]interface{} `json:"dashboard"`
+		Meta      struct {
+			FolderUID string `json:"folderUid"`
+			UpdatedBy string `json:"updatedBy"`
+			Version   int    `json:"version"`
+		} `json:"meta"`
+	}
+	if err := c.Get("/api/dashboards/uid/"+uid, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to fetch dashboard %s: %w", uid, err)
+	}
+	state := &dashboardState{
+		Model:     wrapper.Dashboard,
+		Version:   wrapper.Meta.Version,
+		FolderUID: wrapper.Meta.FolderUID,
+		UpdatedBy: wrapper.Meta.UpdatedBy,
+	}
+	if v, ok := wrapper.Dashboard["version"].(float64); ok && state.Version == 0 {
+		state.Version = int(v)
+	}
+	return state, nil
+}
+
+// saveDashboardUpdate saves model as the next version of base. Unless
+// overwrite is set, Grafana rejects it when base is no longer current.
+func saveDashboardUpdate(c *client.Client, model map[string]interface{}, base *dashboardState, message string, overwrite bool) ([]byte, error) {
+	model["version"] = base.Version
+	payload := map[string]interface{}{
+		"dashboard": model,
+		"overwrite": overwrite,
+	}
+	if base.FolderUID != "" {
+		payload["folderUid"] = base.FolderUID
+	}
+	if message != "" {
+		payload["message"] = message
+	}
+	var body []byte
+	err := c.Post("/api/dashboards/db", payload, &body)
+	return body, err
+}
 
-			fmt.Printf("Dashboard updated successfully.\n%s\n", string(ubody))
-			break
+func isVersionMismatch(apiErr *client.APIError) bool {
+	var body struct {
+		Status string `json:"status"`
+	}
+	json.Unmarshal(apiErr.Body, &body)
+	return apiErr.StatusCode == http.StatusPreconditionFailed && body.Status == "version-mismatch"
+}
+
+// Choices offered when an update hits a newer version.
+const (
+	conflictAbort = iota
+	conflictRefetch
+	conflictForce
+)
+
+// resolveVersionConflict asks how to continue after someone else saved the
+// dashboard, showing a three-way diff on request. It aborts when stdin ends.
+func resolveVersionConflict(reader *bufio.Reader, uid string, base *dashboardState, mine map[string]interface{}, latest *dashboardState) int {
+	by := ""
+	if latest.UpdatedBy != "" {
+		by = " by " + latest.UpdatedBy
+	}
+	fmt.Printf("\nDashboard %s was changed by someone else: you edited version %d, version %d was saved since%s.\n", uid, base.Version, latest.Version, by)
+	for {
+		fmt.Println("  [r] re-fetch the current version and edit it again")
+		fmt.Println("  [d] show a three-way diff")
+		fmt.Println("  [f] force the update, overwriting their changes")
+		fmt.Println("  [a] abort")
+		fmt.Print("Choice [r/d/f/a]: ")
+		answer, err := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "r":
+			return conflictRefetch
+		case "f":
+			return conflictForce
+		case "d":
+			printThreeWayDiff(base, mine, latest)
+			continue
+		case "a":
+			return conflictAbort
 		}
+		if err != nil {
+			fmt.Println()
+			return conflictAbort
+		}
+	}
+}
 
-		return nil
-	},
+func printThreeWayDiff(base *dashboardState, mine map[string]interface{}, latest *dashboardState) {
+	ours := dashboard.Diff(base.Model, mine)
+	theirs := dashboard.Diff(base.Model, latest.Model)
+	fmt.Printf("\nYour changes (version %d -> your edit):\n", base.Version)
+	dashboard.WriteDiff(os.Stdout, ours)
+	fmt.Printf("\nTheir changes (version %d -> version %d):\n", base.Version, latest.Version)
+	dashboard.WriteDiff(os.Stdout, theirs)
+	if conflicts := dashboard.Conflicts(ours, theirs); len(conflicts) > 0 {
+		fmt.Println("\nChanged on both sides:")
+		for _, path := range conflicts {
+			fmt.Println("  " + path)
+		}
+	} else {
+		fmt.Println("\nNo overlapping changes.")
+	}
+	fmt.Println()
+}
+
+// saveEdit keeps an edit that is about to be replaced in a temporary file and
+// returns its path.
+func saveEdit(uid, content string) (string, error) {
+	f, err := os.CreateTemp("", "gcli-dash-"+uid+"-*.json")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
 }
 
 // dash create --file [PATH]
 var dashCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a dashboard from a file",
+	Long: `Create a dashboard from a file, which may be an external template exported
+with 'dash read --external'.
+
+A template's datasource inputs are mapped with --map or --mapping-file, or by
+--auto-map, which picks the only datasource of the required type. Constant
+inputs take their --map value or their default. The title and UID come from
+the file unless --title or --uid is given.
+
+Prompts appear only when stdin is a terminal and a value is missing, so the
+command runs unattended in CI.`,
+	Example: `  gcli dash create --file dash.json
+  gcli dash create --file template.json --map DS_PROMETHEUS=Prometheus --folder ops --title "Nodes (prod)"
+  gcli dash create --file template.json --auto-map --uid nodes-prod --overwrite
+  gcli dash create --file template.json --mapping-file prod-datasources.yaml`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		filePath, _ := cmd.Flags().GetString("file")
 		if filePath == "" {
-			return fmt.Errorf("--file flag is required")
+			return &usageError{fmt.Errorf("--file is required")}
+		}
+		mappings, err := templateMappings(cmd)
+		if err != nil {
+			return err
 		}
+		autoMap, _ := cmd.Flags().GetBool("auto-map")
+		folder, _ := cmd.Flags().GetString("folder")
+		overwrite, _ := cmd.Flags().GetBool("overwrite")
+		interactive := stdinIsTerminal()
 
 		data, err := os.ReadFile(filePath)
 		if err != nil {
@@ -475,87 +508,25 @@ var dashCreateCmd = &cobra.Command{
 			return fmt.Errorf("invalid dashboard JSON: %w", err)
 		}
 
-		profile, err := config.GetActive()
+		c, err := client.FromActive()
 		if err != nil {
 			return err
 		}
-		activeOrg, _ := config.GetActiveOrg()
+		reader := bufio.NewReader(os.Stdin)
 
 		// Check for external template inputs (exported dashboards often have this)
 		if inputs, ok := dashRaw["__inputs"].([]interface{}); ok && len(inputs) > 0 {
-			fmt.Println("This dashboard is an external template and requires datasource mapping.")
-
-			// Map to hold our final mappings
-			mappings := make(map[string]string)
-
-			// Fetch available datasources for the active org
-			dsURL := fmt.Sprintf("%s/api/datasources", profile.URL)
-			dsReq, _ := http.NewRequest(http.MethodGet, dsURL, nil)
-			dsReq.SetBasicAuth(profile.User, profile.Pass)
-			if activeOrg != "" {
-				dsReq.Header.Set("X-Grafana-Org-Id", activeOrg)
-			}
-			dsResp, err := http.DefaultClient.Do(dsReq)
+			values, err := resolveTemplateInputs(c, inputs, mappings, autoMap, interactive, reader)
 			if err != nil {
 				return err
 			}
-			defer dsResp.Body.Close()
-			var availableDS []struct {
-				UID  string `json:"uid"`
-				Name string `json:"name"`
-				Type string `json:"type"`
-			}
-			json.NewDecoder(dsResp.Body).Decode(&availableDS)
-
-			reader := bufio.NewReader(os.Stdin)
-
-			for _, input := range inputs {
-				im := input.(map[string]interface{})
-				inputType, _ := im["type"].(string)
-				inputName, _ := im["name"].(string)
-				inputLabel, _ := im["label"].(string)
-				pluginID, _ := im["pluginId"].(string)
-
-				if inputType == "datasource" {
-					fmt.Printf("\nSelect datasource for '%s' (%s, plugin: %s):\n", inputLabel, inputName, pluginID)
-
-					// Filter datasources by pluginID (type)
-					var filtered []int
-					for i, ds := range availableDS {
-						if ds.Type == pluginID {
-							fmt.Printf("[%d] %s (UID: %s)\n", len(filtered)+1, ds.Name, ds.UID)
-							filtered = append(filtered, i)
-						}
-					}
-
-					if len(filtered) == 0 {
-						return fmt.Errorf("no datasources found for type %s", pluginID)
-					}
-
-					for {
-						fmt.Print("Enter number: ")
-						inputStr, _ := reader.ReadString('\n')
-						inputStr = strings.TrimSpace(inputStr)
-						idx, err := strconv.Atoi(inputStr)
-						if err == nil && idx > 0 && idx <= len(filtered) {
-							mappings[inputName] = availableDS[filtered[idx-1]].UID
-							break
-						}
-						fmt.Println("Invalid selection. Please try again.")
-					}
-				}
-			}
-
-			// Now we need to replace occurrences of these template variables in the dashboard spec.
-			// Actually, external templates use ${VAR_NAME} syntax.
-			// We can do a string replacement on the entire raw JSON for simplicity if we are careful,
-			// but better to walk the tree or use a regex.
-			// For simplicity and speed in a CLI, string replacement of ${NAME} is common for Grafana templates.
 
+			// External templates use ${VAR_NAME} syntax; a string replacement
+			// on the raw JSON covers every place they can appear.
 			jsonStr := string(data)
-			for name, uid := range mappings {
+			for name, value := range values {
 				target := fmt.Sprintf("${%s}", name)
-				jsonStr = strings.ReplaceAll(jsonStr, target, uid)
+				jsonStr = strings.ReplaceAll(jsonStr, target, value)
 			}
 
 			// Re-parse it
@@ -566,63 +537,224 @@ var dashCreateCmd = &cobra.Command{
 			// Remove __inputs and __requires as they are for templates, not for direct import
 			delete(dashRaw, "__inputs")
 			delete(dashRaw, "__requires")
+		} else if len(mappings) > 0 {
+			return &usageError{fmt.Errorf("--map and --mapping-file need a template with __inputs")}
 		}
 
-		// Interactive Prompts for Title and UID
-		fmt.Println() // New line for spacing
-		reader := bufio.NewReader(os.Stdin)
-
-		currTitle := dashRaw["title"]
-		fmt.Printf("Change title? (current: %v) [y/N]: ", currTitle)
-		ans, _ := reader.ReadString('\n')
-		if strings.ToLower(strings.TrimSpace(ans)) == "y" {
-			fmt.Print("Enter new title: ")
-			newName, _ := reader.ReadString('\n')
-			dashRaw["title"] = strings.TrimSpace(newName)
+		// Only values missing from both the flags and the file are asked for.
+		if cmd.Flags().Changed("title") {
+			dashRaw["title"], _ = cmd.Flags().GetString("title")
+		} else if title, _ := dashRaw["title"].(string); title == "" && interactive {
+			fmt.Print("Enter title: ")
+			answer, _ := reader.ReadString('\n')
+			dashRaw["title"] = strings.TrimSpace(answer)
 		}
 
-		currUID := dashRaw["uid"]
-		displayUID := currUID
-		if displayUID == nil || displayUID == "" {
-			displayUID = "(none, will be auto-generated)"
-		}
-		fmt.Printf("Change UID? (current: %v) [y/N]: ", displayUID)
-		ans, _ = reader.ReadString('\n')
-		if strings.ToLower(strings.TrimSpace(ans)) == "y" {
-			fmt.Print("Enter new UID: ")
-			newUID, _ := reader.ReadString('\n')
-			dashRaw["uid"] = strings.TrimSpace(newUID)
+		if cmd.Flags().Changed("uid") {
+			dashRaw["uid"], _ = cmd.Flags().GetString("uid")
+		} else if uid, _ := dashRaw["uid"].(string); uid == "" && interactive {
+			fmt.Print("Enter UID (empty to auto-generate): ")
+			answer, _ := reader.ReadString('\n')
+			if answer = strings.TrimSpace(answer); answer != "" {
+				dashRaw["uid"] = answer
+			}
 		}
+		// The id belongs to the instance the file came from.
+		delete(dashRaw, "id")
 
 		// Prepare create payload
 		payload := map[string]interface{}{
 			"dashboard": dashRaw,
-			"overwrite": false,
+			"overwrite": overwrite,
+		}
+		if folder != "" {
+			folderUID, err := resolveFolderUID(c, folder)
+			if err != nil {
+				return err
+			}
+			payload["folderUid"] = folderUID
 		}
 
-		payloadBytes, _ := json.Marshal(payload)
-		createURL := fmt.Sprintf("%s/api/dashboards/db", profile.URL)
-		req, _ := http.NewRequest(http.MethodPost, createURL, bytes.NewReader(payloadBytes))
-		req.Header.Set("Content-Type", "application/json")
-		req.SetBasicAuth(profile.User, profile.Pass)
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
+		var body []byte
+		if err := c.Post("/api/dashboards/db", payload, &body); err != nil {
+			return fmt.Errorf("create failed: %w", err)
 		}
 
-		resp, err := http.DefaultClient.Do(req)
+		fmt.Printf("Dashboard created successfully.\n%s\n", string(body))
+		return nil
+	},
+}
+
+// templateMappings returns the input values given with --mapping-file and
+// --map, the flags taking precedence.
+func templateMappings(cmd *cobra.Command) (map[string]string, error) {
+	mappings := map[string]string{}
+	if path, _ := cmd.Flags().GetString("mapping-file"); path != "" {
+		data, err := os.ReadFile(path)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		// YAML also covers JSON files.
+		if err := yaml.Unmarshal(data, &mappings); err != nil {
+			return nil, fmt.Errorf("invalid mapping file %s: %w", path, err)
+		}
+	}
+	flags, _ := cmd.Flags().GetStringArray("map")
+	for _, m := range flags {
+		name, value, ok := strings.Cut(m, "=")
+		if !ok || name == "" || value == "" {
+			return nil, &usageError{fmt.Errorf("invalid --map %q, expected NAME=<name|uid>", m)}
 		}
-		defer resp.Body.Close()
+		mappings[name] = value
+	}
+	return mappings, nil
+}
+
+type templateDatasource struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// resolveTemplateInputs returns the value of each template input: the UID of
+// the datasource chosen for datasource inputs, the mapped or default value
+// for constants. Datasources without a mapping are picked by autoMap, or
+// else asked for when interactive.
+func resolveTemplateInputs(c *client.Client, inputs []interface{}, mappings map[string]string, autoMap, interactive bool, reader *bufio.Reader) (map[string]string, error) {
+	// Fetch available datasources for the active org
+	var availableDS []templateDatasource
+	if err := c.Get("/api/datasources", &availableDS); err != nil {
+		return nil, err
+	}
 
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-			return fmt.Errorf("create failed: %s %s", resp.Status, string(body))
+	values := make(map[string]string)
+	for _, input := range inputs {
+		im, _ := input.(map[string]interface{})
+		inputType, _ := im["type"].(string)
+		inputName, _ := im["name"].(string)
+		inputLabel, _ := im["label"].(string)
+		pluginID, _ := im["pluginId"].(string)
+
+		if inputType == "constant" {
+			if v, ok := mappings[inputName]; ok {
+				values[inputName] = v
+			} else if v, ok := im["value"].(string); ok {
+				values[inputName] = v
+			}
+			continue
+		}
+		if inputType != "datasource" {
+			continue
 		}
 
-		fmt.Printf("Dashboard created successfully.\n%s\n", string(body))
-		return nil
-	},
+		// Filter datasources by pluginID (type)
+		var candidates []templateDatasource
+		for _, ds := range availableDS {
+			if ds.Type == pluginID {
+				candidates = append(candidates, ds)
+			}
+		}
+
+		if want, ok := mappings[inputName]; ok {
+			ds, err := findMappedDatasource(availableDS, inputName, want, pluginID)
+			if err != nil {
+				return nil, err
+			}
+			values[inputName] = ds.UID
+			continue
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no datasources found for type %s (input %s)", pluginID, inputName)
+		}
+		if autoMap {
+			if len(candidates) > 1 {
+				names := make([]string, len(candidates))
+				for i, ds := range candidates {
+					names[i] = ds.Name
+				}
+				return nil, fmt.Errorf("cannot auto-map %s: %d %s datasources (%s); use --map %s=<name|uid>",
+					inputName, len(candidates), pluginID, strings.Join(names, ", "), inputName)
+			}
+			values[inputName] = candidates[0].UID
+			continue
+		}
+		if !interactive {
+			return nil, fmt.Errorf("no datasource given for input %s (%s); use --map %s=<name|uid> or --auto-map", inputName, pluginID, inputName)
+		}
+
+		fmt.Printf("\nSelect datasource for '%s' (%s, plugin: %s):\n", inputLabel, inputName, pluginID)
+		for i, ds := range candidates {
+			fmt.Printf("[%d] %s (UID: %s)\n", i+1, ds.Name, ds.UID)
+		}
+		for {
+			fmt.Print("Enter number: ")
+			inputStr, err := reader.ReadString('\n')
+			idx, convErr := strconv.Atoi(strings.TrimSpace(inputStr))
+			if convErr == nil && idx > 0 && idx <= len(candidates) {
+				values[inputName] = candidates[idx-1].UID
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("no datasource selected for input %s", inputName)
+			}
+			fmt.Println("Invalid selection. Please try again.")
+		}
+	}
+
+	for name := range mappings {
+		if _, ok := values[name]; !ok && !hasInput(inputs, name) {
+			fmt.Fprintf(os.Stderr, "Warning: the template has no input %s\n", name)
+		}
+	}
+	return values, nil
+}
+
+// findMappedDatasource finds the datasource a mapping names, by UID or name,
+// and checks it has the type the input requires.
+func findMappedDatasource(available []templateDatasource, input, want, pluginID string) (templateDatasource, error) {
+	for _, ds := range available {
+		if ds.UID != want && ds.Name != want {
+			continue
+		}
+		if pluginID != "" && ds.Type != pluginID {
+			return ds, fmt.Errorf("%s maps to %s, a %s datasource, but the template needs %s", input, ds.Name, ds.Type, pluginID)
+		}
+		return ds, nil
+	}
+	return templateDatasource{}, fmt.Errorf("datasource %q for input %s not found", want, input)
+}
+
+func hasInput(inputs []interface{}, name string) bool {
+	for _, input := range inputs {
+		if im, ok := input.(map[string]interface{}); ok && im["name"] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFolderUID returns the UID of a folder given by UID or title.
+func resolveFolderUID(c *client.Client, folder string) (string, error) {
+	err := c.Get("/api/folders/"+url.PathEscape(folder), nil)
+	if err == nil {
+		return folder, nil
+	}
+	if !errors.Is(err, client.ErrNotFound) {
+		return "", fmt.Errorf("failed to fetch folder %s: %w", folder, err)
+	}
+	var folders []struct {
+		UID   string `json:"uid"`
+		Title string `json:"title"`
+	}
+	if err := c.GetAll("/api/folders", 0, &folders); err != nil {
+		return "", fmt.Errorf("failed to list folders: %w", err)
+	}
+	for _, f := range folders {
+		if strings.EqualFold(f.Title, folder) {
+			return f.UID, nil
+		}
+	}
+	return "", fmt.Errorf("folder %s not found", folder)
 }
 
 func init() {
@@ -631,9 +763,20 @@ func init() {
 	dashCmd.AddCommand(dashRmCmd)
 	dashCmd.AddCommand(dashUpdateCmd)
 	dashCmd.AddCommand(dashCreateCmd)
+	dashListCmd.Flags().Int("limit", 0, "Maximum number of dashboards to list (default all)")
 	dashListCmd.Flags().Bool("details", false, "Show detailed JSON output")
+	dashListCmd.Flags().MarkDeprecated("details", "use -o json instead")
 	dashReadCmd.Flags().Bool("external", false, "Export dashboard for sharing (external template)")
 	dashCreateCmd.Flags().String("file", "", "JSON file containing dashboard definition")
+	dashCreateCmd.Flags().String("title", "", "Dashboard title, instead of the one in the file")
+	dashCreateCmd.Flags().String("uid", "", "Dashboard UID, instead of the one in the file")
+	dashCreateCmd.Flags().String("folder", "", "Folder UID or title to create the dashboard in (default General)")
+	dashCreateCmd.Flags().Bool("overwrite", false, "Replace an existing dashboard with the same UID or title")
+	dashCreateCmd.Flags().StringArray("map", nil, "Template input value as NAME=<datasource name|uid> (repeatable)")
+	dashCreateCmd.Flags().String("mapping-file", "", "YAML or JSON file mapping template input names to datasources")
+	dashCreateCmd.Flags().Bool("auto-map", false, "Map each datasource input to the only datasource of its type")
+	dashCreateCmd.MarkFlagRequired("file")
+	dashUpdateCmd.Flags().String("message", "", "Version message describing the change")
 }
 
 func discoverDatasourceUIDs(v interface{}, uids map[string]bool) {
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"gcli/internal/client"
+	"gcli/internal/dashboard"
+
+	"github.com/spf13/cobra"
+)
+
+// dash diff [UID] --file [PATH] | --to-profile [NAME]
+var dashDiffCmd = &cobra.Command{
+	Use:   "diff [UID]",
+	Short: "Compare a dashboard with a local file or another profile",
+	Long: `Compare a dashboard in the active profile with a local file or with the
+same dashboard in another profile.
+
+Both sides are compared without their id, version and iteration fields.
+Objects are compared by key and arrays by position, so panels keep their
+order. Each difference is printed with its path and, inside panels, the panel
+title; lines starting with - show the active profile's value and + the other
+side's.
+
+The exit status is 0 when the dashboards match, 1 when they differ and 2 or
+higher when the comparison failed, so the command can check for drift in CI.`,
+	Example: `  gcli dash diff cpu --file dashboards/operations/cpu.json
+  gcli dash diff cpu --to-profile staging`,
+	Args: cobra.ExactArgs(1),
+	RunE: comparison(func(cmd *cobra.Command, args []string) error {
+		uid := args[0]
+		file, _ := cmd.Flags().GetString("file")
+		profile, _ := cmd.Flags().GetString("to-profile")
+		if (file == "") == (profile == "") {
+			return &usageError{fmt.Errorf("exactly one of --file and --to-profile is required")}
+		}
+
+		c, err := client.FromActive()
+		if err != nil {
+			return err
+		}
+		current, err := fetchDashboard(c, uid)
+		if err != nil {
+			return err
+		}
+
+		var other map[string]interface{}
+		label := file
+		if file != "" {
+			if other, err = readDashboardFile(file); err != nil {
+				return err
+			}
+		} else {
+			oc, err := client.FromProfile(profile)
+			if err != nil {
+				return err
+			}
+			theirs, err := fetchDashboard(oc, uid)
+			if err != nil {
+				return fmt.Errorf("profile %s: %w", profile, err)
+			}
+			other = theirs.Model
+			label = "profile " + profile
+		}
+
+		changes := dashboard.Diff(current.Model, other)
+		if len(changes) == 0 {
+			fmt.Println("No differences.")
+			return nil
+		}
+		fmt.Printf("--- dashboard %s\n+++ %s\n", uid, label)
+		dashboard.WriteDiff(os.Stdout, changes)
+		return &silentError{exitError}
+	}),
+}
+
+// comparison wraps the RunE of a command exiting 1 on differences, so that
+// its errors exit with comparisonError's codes instead.
+func comparison(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		err := run(cmd, args)
+		var differ *silentError
+		if err == nil || errors.As(err, &differ) {
+			return err
+		}
+		return &comparisonError{err}
+	}
+}
+
+// readDashboardFile reads a dashboard model from a file. Files holding a full
+// API response, with the model under "dashboard", are accepted too.
+func readDashboardFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var model map[string]interface{}
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("invalid dashboard JSON in %s: %w", path, err)
+	}
+	if inner, ok := model["dashboard"].(map[string]interface{}); ok {
+		if _, ok := model["meta"]; ok {
+			return inner, nil
+		}
+	}
+	return model, nil
+}
+
+func init() {
+	dashCmd.AddCommand(dashDiffCmd)
+	dashDiffCmd.Flags().String("file", "", "Dashboard JSON file to compare with")
+	dashDiffCmd.Flags().String("to-profile", "", "Profile whose copy of the dashboard to compare with")
+}
@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gcli/internal/client"
+	"gcli/internal/dashboard"
+
+	"github.com/spf13/cobra"
+)
+
+// dash pull --dir [DIR]
+var dashPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Export every dashboard of the active organization to a directory",
+	Long: `Export every dashboard of the active organization to a directory.
+
+Each Grafana folder becomes a subdirectory holding a .folder.json file with
+the folder's uid and title, with the uid appended to the directory name when
+two folder titles make the same name; dashboards in the General folder go to
+the top level. Each dashboard is written to <title>.json without its id and version,
+with keys sorted, so the directory can be kept in git. Files of dashboards
+that were renamed or moved are replaced; unchanged files are not rewritten.`,
+	Example: `  gcli dash pull --dir ./dashboards`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		c, err := client.FromActive()
+		if err != nil {
+			return err
+		}
+
+		var items []struct {
+			UID       string `json:"uid"`
+			Title     string `json:"title"`
+			FolderUID string `json:"folderUid"`
+		}
+		if err := c.GetAll("/api/search?type=dash-db", 0, &items); err != nil {
+			return fmt.Errorf("list failed: %w", err)
+		}
+
+		// Files already in the directory, by dashboard uid.
+		existing := map[string]string{}
+		if _, err := os.Stat(dir); err == nil {
+			files, err := dashboard.ReadDir(dir)
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				if uid := f.UID(); uid != "" {
+					existing[uid] = filepath.Join(dir, filepath.FromSlash(f.Path))
+				}
+			}
+		}
+
+		folderDirs := map[string]string{"": dir}
+		// Folder uids by directory, to keep folders whose titles make the
+		// same file name apart.
+		dirFolders := map[string]string{}
+		used := map[string]bool{}
+		// Old files of renamed or moved dashboards, removed once every
+		// dashboard is written: another one may have taken the name.
+		var stale []string
+		changed := 0
+		for _, item := range items {
+			folderDir, ok := folderDirs[item.FolderUID]
+			if !ok {
+				chain, err := folderChain(c, item.FolderUID)
+				if err != nil {
+					return err
+				}
+				folderDir = dir
+				for _, f := range chain {
+					name := dashboard.FileName(f.Title)
+					if name == "" {
+						name = f.UID
+					}
+					path := filepath.Join(folderDir, name)
+					if uid, ok := dirFolders[path]; ok && uid != f.UID {
+						path = filepath.Join(folderDir, name+"-"+f.UID)
+					}
+					dirFolders[path] = f.UID
+					folderDir = path
+					if _, err := dashboard.WriteFile(filepath.Join(folderDir, dashboard.FolderFile), f); err != nil {
+						return err
+					}
+				}
+				folderDirs[item.FolderUID] = folderDir
+			}
+
+			current, err := fetchDashboard(c, item.UID)
+			if err != nil {
+				return err
+			}
+
+			name := dashboard.FileName(item.Title)
+			if name == "" || used[filepath.Join(folderDir, name+".json")] {
+				name = strings.TrimPrefix(name+"-"+item.UID, "-")
+			}
+			path := filepath.Join(folderDir, name+".json")
+			used[path] = true
+
+			wrote, err := dashboard.WriteFile(path, dashboard.Strip(current.Model, dashboard.InstanceFields...))
+			if err != nil {
+				return err
+			}
+			if old, ok := existing[item.UID]; ok && old != path {
+				stale = append(stale, old)
+				wrote = true
+			}
+			if wrote {
+				changed++
+				fmt.Printf("wrote %s\n", path)
+			}
+		}
+
+		for _, path := range stale {
+			if used[path] {
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Pulled %d dashboards into %s (%d changed)\n", len(items), dir, changed)
+		return nil
+	},
+}
+
+// folderChain returns a folder and its parents, outermost first. Parents are
+// only reported by Grafana versions with nested folders.
+func folderChain(c *client.Client, uid string) ([]dashboard.Folder, error) {
+	var f struct {
+		UID     string             `json:"uid"`
+		Title   string             `json:"title"`
+		Parents []dashboard.Folder `json:"parents"`
+	}
+	if err := c.Get("/api/folders/"+url.PathEscape(uid), &f); err != nil {
+		return nil, fmt.Errorf("failed to fetch folder %s: %w", uid, err)
+	}
+	return append(f.Parents, dashboard.Folder{UID: f.UID, Title: f.Title}), nil
+}
+
+// dash push --dir [DIR]
+var dashPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Create or update dashboards from a directory",
+	Long: `Create or update dashboards from a directory written by dash pull.
+
+Dashboards are matched by uid, so pushing the same directory twice changes
+nothing. Dashboards that already match Grafana are skipped, and folders are
+created when missing. Subdirectories without a .folder.json file are matched
+to folders by title.`,
+	Example: `  gcli dash push --dir ./dashboards
+  gcli dash push --dir ./dashboards --message "Deploy $(git rev-parse --short HEAD)"
+  gcli dash push --dir ./dashboards --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		message, _ := cmd.Flags().GetString("message")
+
+		files, err := dashboard.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		// Check every file before changing anything.
+		paths := map[string]string{}
+		for _, f := range files {
+			uid := f.UID()
+			if uid == "" {
+				return fmt.Errorf("%s: dashboard has no uid", f.Path)
+			}
+			if other, ok := paths[uid]; ok {
+				return fmt.Errorf("%s and %s have the same uid %q", other, f.Path, uid)
+			}
+			paths[uid] = f.Path
+		}
+
+		c, err := client.FromActive()
+		if err != nil {
+			return err
+		}
+		folders := &folderResolver{c: c, uids: map[string]string{}, pending: map[string]bool{}}
+
+		var created, updated, unchanged int
+		for _, f := range files {
+			folderUID, err := folders.resolve(f.Folders)
+			if err != nil {
+				return err
+			}
+			current, err := fetchDashboard(c, f.UID())
+			exists := err == nil
+			if err != nil && !errors.Is(err, client.ErrNotFound) {
+				return fmt.Errorf("%s: %w", f.Path, err)
+			}
+
+			model := dashboard.Strip(f.Model, dashboard.InstanceFields...)
+			if exists && current.FolderUID == folderUID &&
+				reflect.DeepEqual(dashboard.Strip(current.Model, dashboard.InstanceFields...), model) {
+				unchanged++
+				continue
+			}
+
+			payload := map[string]interface{}{
+				"dashboard": model,
+				"folderUid": folderUID,
+				"overwrite": true,
+			}
+			if message != "" {
+				payload["message"] = message
+			}
+			if err := c.Post("/api/dashboards/db", payload, nil); err != nil && !notSent(err) {
+				return fmt.Errorf("%s: %w", f.Path, err)
+			}
+			if exists {
+				updated++
+				fmt.Printf("updated %s\n", f.Path)
+			} else {
+				created++
+				fmt.Printf("created %s\n", f.Path)
+			}
+		}
+
+		fmt.Printf("Pushed %d dashboards: %d created, %d updated, %d unchanged\n", len(files), created, updated, unchanged)
+		return nil
+	},
+}
+
+// folderResolver finds or creates the folders of pushed dashboards.
+type folderResolver struct {
+	c *client.Client
+	// uids maps a chain of folders, joined by "/", to the folder uid.
+	uids map[string]string
+	// pending holds the uids of folders that --dry-run or --as-curl only
+	// pretended to create.
+	pending map[string]bool
+}
+
+// resolve returns the uid of the innermost folder of chain, creating missing
+// folders, or "" for the General folder.
+func (r *folderResolver) resolve(chain []dashboard.Folder) (string, error) {
+	parent, key := "", ""
+	for _, f := range chain {
+		key += "/" + f.UID + ":" + f.Title
+		uid, ok := r.uids[key]
+		if !ok {
+			var err error
+			if uid, err = r.ensure(f, parent); err != nil {
+				return "", err
+			}
+			r.uids[key] = uid
+		}
+		parent = uid
+	}
+	return parent, nil
+}
+
+func (r *folderResolver) ensure(f dashboard.Folder, parent string) (string, error) {
+	if f.UID != "" {
+		err := r.c.Get("/api/folders/"+url.PathEscape(f.UID), nil)
+		if err == nil {
+			return f.UID, nil
+		}
+		if !errors.Is(err, client.ErrNotFound) {
+			return "", fmt.Errorf("failed to fetch folder %s: %w", f.UID, err)
+		}
+	} else if !r.pending[parent] {
+		path := "/api/folders"
+		if parent != "" {
+			path += "?parentUid=" + url.QueryEscape(parent)
+		}
+		var list []dashboard.Folder
+		if err := r.c.GetAll(path, 0, &list); err != nil {
+			return "", fmt.Errorf("failed to list folders: %w", err)
+		}
+		for _, existing := range list {
+			if strings.EqualFold(existing.Title, f.Title) {
+				return existing.UID, nil
+			}
+		}
+	}
+
+	payload := map[string]string{"uid": f.UID, "title": f.Title}
+	if parent != "" {
+		payload["parentUid"] = parent
+	}
+	var created dashboard.Folder
+	if err := r.c.Post("/api/folders", payload, &created); err != nil {
+		if notSent(err) {
+			// Grafana would pick the uid; show where the dashboards go
+			// rather than an empty uid, which means the General folder.
+			uid := f.UID
+			if uid == "" {
+				uid = "(new folder " + f.Title + ")"
+			}
+			r.pending[uid] = true
+			fmt.Printf("would create folder %s\n", f.Title)
+			return uid, nil
+		}
+		return "", fmt.Errorf("failed to create folder %q: %w", f.Title, err)
+	}
+	fmt.Printf("created folder %s\n", f.Title)
+	return created.UID, nil
+}
+
+// notSent reports whether err only means a write was printed instead of sent,
+// with --dry-run or --as-curl, so a command can go on to its next write.
+func notSent(err error) bool {
+	return errors.Is(err, client.ErrDryRun) || errors.Is(err, client.ErrCurlOnly)
+}
+
+func init() {
+	dashCmd.AddCommand(dashPullCmd)
+	dashCmd.AddCommand(dashPushCmd)
+	dashPullCmd.Flags().String("dir", ".", "Directory to write dashboards to")
+	dashPushCmd.Flags().String("dir", ".", "Directory to read dashboards from")
+	dashPushCmd.Flags().String("message", "", "Version message for created and updated dashboards")
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gcli/internal/client"
+	"gcli/internal/dashboard"
+	"gcli/internal/output"
+
+	"github.com/spf13/cobra"
+)
+
+// dashboardVersion is an entry of a dashboard's version history.
+type dashboardVersion struct {
+	Version      int    `json:"version"`
+	Created      string `json:"created"`
+	CreatedBy    string `json:"createdBy"`
+	Message      string `json:"message"`
+	RestoredFrom int    `json:"restoredFrom"`
+}
+
+// dash versions [UID]
+var dashVersionsCmd = &cobra.Command{
+	Use:   "versions [UID]",
+	Short: "List the saved versions of a dashboard",
+	Example: `  gcli dash versions cpu
+  gcli dash versions diff cpu 3 5
+  gcli dash restore cpu --version 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		uid := args[0]
+		limit, _ := cmd.Flags().GetInt("limit")
+		printer, err := newPrinter(cmd, output.Table)
+		if err != nil {
+			return err
+		}
+		c, err := client.FromActive()
+		if err != nil {
+			return err
+		}
+
+		path := "/api/dashboards/uid/" + uid + "/versions"
+		if limit > 0 {
+			path += "?limit=" + strconv.Itoa(limit)
+		}
+		var body []byte
+		if err := c.Get(path, &body); err != nil {
+			return fmt.Errorf("failed to fetch versions: %w", err)
+		}
+		// Grafana 11 wraps the list in an object with a continueToken.
+		items := json.RawMessage(body)
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+			var paged struct {
+				Versions json.RawMessage `json:"versions"`
+			}
+			if err := json.Unmarshal(body, &paged); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+			items = paged.Versions
+		}
+		var versions []dashboardVersion
+		if err := json.Unmarshal(items, &versions); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		table := &output.TableData{Columns: []output.Column{
+			{Header: "Version"},
+			{Header: "Date"},
+			{Header: "Author"},
+			{Header: "Message"},
+			{Header: "Restored From", Wide: true},
+		}}
+		for _, v := range versions {
+			restored := ""
+			if v.RestoredFrom > 0 {
+				restored = strconv.Itoa(v.RestoredFrom)
+			}
+			table.AddRow(strconv.Itoa(v.Version), formatVersionDate(v.Created), v.CreatedBy, v.Message, restored)
+		}
+		return printer.Print(items, table)
+	},
+}
+
+// formatVersionDate shortens an RFC 3339 timestamp to local time, leaving
+// other formats as they are.
+func formatVersionDate(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// dash versions diff [UID] [VERSION] [VERSION]
+var dashVersionsDiffCmd = &cobra.Command{
+	Use:   "diff [UID] [VERSION] [VERSION]",
+	Short: "Compare two saved versions of a dashboard",
+	Long: `Compare two saved versions of a dashboard, in the format of dash diff.
+Lines starting with - show the first version's value and + the second's. The
+exit status is 1 when the versions differ and 2 or higher when the comparison
+failed.`,
+	Example: `  gcli dash versions diff cpu 3 5`,
+	Args:    cobra.ExactArgs(3),
+	RunE: comparison(func(cmd *cobra.Command, args []string) error {
+		uid := args[0]
+		var versions [2]int
+		for i, arg := range args[1:] {
+			v, err := strconv.Atoi(arg)
+			if err != nil || v < 1 {
+				return &usageError{fmt.Errorf("invalid version %q", arg)}
+			}
+			versions[i] = v
+		}
+
+		c, err := client.FromActive()
+		if err != nil {
+			return err
+		}
+		var models [2]map[string]interface{}
+		for i, v := range versions {
+			if models[i], err = fetchDashboardVersion(c, uid, v); err != nil {
+				return err
+			}
+		}
+
+		changes := dashboard.Diff(models[0], models[1])
+		if len(changes) == 0 {
+			fmt.Println("No differences.")
+			return nil
+		}
+		fmt.Printf("--- version %d\n+++ version %d\n", versions[0], versions[1])
+		dashboard.WriteDiff(os.Stdout, changes)
+		return &silentError{exitError}
+	}),
+}
+
+// fetchDashboardVersion returns the model saved as a version of a dashboard.
+func fetchDashboardVersion(c *client.Client, uid string, version int) (map[string]interface{}, error) {
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := c.Get(fmt.Sprintf("/api/dashboards/uid/%s/versions/%d", uid, version), &body); err != nil {
+		return nil, fmt.Errorf("failed to fetch version %d: %w", version, err)
+	}
+	return body.Data, nil
+}
+
+// dash restore [UID] --version [N]
+var dashRestoreCmd = &cobra.Command{
+	Use:   "restore [UID]",
+	Short: "Restore a saved version of a dashboard",
+	Long: `Restore a saved version of a dashboard. Grafana saves the old content as a
+new version, so the restore itself can be undone the same way.`,
+	Example: `  gcli dash versions cpu
+  gcli dash restore cpu --version 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		uid := args[0]
+		version, _ := cmd.Flags().GetInt("version")
+		if version < 1 {
+			return &usageError{fmt.Errorf("--version is required")}
+		}
+
+		c, err := client.FromActive()
+		if err != nil {
+			return err
+		}
+		var resp struct {
+			Version int `json:"version"`
+		}
+		path := "/api/dashboards/uid/" + uid + "/restore"
+		if err := c.Call(http.MethodPost, path, map[string]int{"version": version}, &resp); err != nil {
+			return fmt.Errorf("restore failed: %w", err)
+		}
+		fmt.Printf("Dashboard %s restored to version %d (saved as version %d)\n", uid, version, resp.Version)
+		return nil
+	},
+}
+
+func init() {
+	dashCmd.AddCommand(dashVersionsCmd)
+	dashCmd.AddCommand(dashRestoreCmd)
+	dashVersionsCmd.AddCommand(dashVersionsDiffCmd)
+	dashVersionsCmd.Flags().Int("limit", 0, "Maximum number of versions to list, newest first")
+	dashRestoreCmd.Flags().Int("version", 0, "Version to restore")
+}
@@ -1,17 +1,17 @@
 package cmd
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
+	"strconv"
 
-	"gcli/internal/config"
+	"gcli/internal/client"
 	"gcli/internal/datasource"
+	"gcli/internal/output"
 
 	"github.com/spf13/cobra"
 )
@@ -26,68 +26,50 @@ var dsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List data sources for the active profile",
 	RunE: func(cmd *cobra.Command, args []string) error {
-
-		profile, err := config.GetActive()
+		printer, err := newPrinter(cmd, output.Table)
 		if err != nil {
 			return err
 		}
-		if profile == nil {
-			return fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
-		}
-
-		url := fmt.Sprintf("%s/api/datasources", profile.URL)
-		req, err := http.NewRequest(http.MethodGet, url, nil)
+		c, err := client.FromActive()
 		if err != nil {
 			return err
 		}
-		req.SetBasicAuth(profile.User, profile.Pass)
-
-		// Set Org ID header if active org is selected
-		activeOrg, _ := config.GetActiveOrg()
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
-		}
 
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("list failed: %s", resp.Status)
-		}
-
-		details, _ := cmd.Flags().GetBool("details")
-		if details {
-			var pretty bytes.Buffer
-			if err := json.Indent(&pretty, body, "", "  "); err != nil {
-				fmt.Printf("Status: %s\n%s\n", resp.Status, string(body))
-			} else {
-				fmt.Printf("Status: %s\n%s\n", resp.Status, pretty.String())
-			}
-			return nil
+		var body []byte
+		if err := c.Get("/api/datasources", &body); err != nil {
+			return fmt.Errorf("list failed: %w", err)
 		}
 
 		// Tabular output
 		var dss []struct {
-			ID    int    `json:"id"`
-			OrgID int    `json:"orgId"`
-			Name  string `json:"name"`
-			Type  string `json:"type"`
-			URL   string `json:"url"`
+			ID        int    `json:"id"`
+			UID       string `json:"uid"`
+			OrgID     int    `json:"orgId"`
+			Name      string `json:"name"`
+			Type      string `json:"type"`
+			URL       string `json:"url"`
+			Access    string `json:"access"`
+			IsDefault bool   `json:"isDefault"`
 		}
 		if err := json.Unmarshal(body, &dss); err != nil {
 			return fmt.Errorf("failed to parse response: %w", err)
 		}
 
-		fmt.Printf("%-5s %-5s %-30s %-15s %s\n", "ID", "OrgID", "Name", "Type", "URL")
-		fmt.Println("--------------------------------------------------------------------------------")
+		table := &output.TableData{Columns: []output.Column{
+			{Header: "ID"},
+			{Header: "OrgID"},
+			{Header: "Name"},
+			{Header: "Type"},
+			{Header: "URL"},
+			{Header: "UID", Wide: true},
+			{Header: "Access", Wide: true},
+			{Header: "Default", Wide: true},
+		}}
 		for _, ds := range dss {
-			fmt.Printf("%-5d %-5d %-30s %-15s %s\n", ds.ID, ds.OrgID, ds.Name, ds.Type, ds.URL)
+			table.AddRow(strconv.Itoa(ds.ID), strconv.Itoa(ds.OrgID), ds.Name, ds.Type, ds.URL,
+				ds.UID, ds.Access, strconv.FormatBool(ds.IsDefault))
 		}
-		return nil
+		return printer.Print(body, table)
 	},
 }
 
@@ -127,36 +109,13 @@ var dsRmCmd = &cobra.Command{
 			return err
 		}
 
-		profile, _ := config.GetActive() // Checked inside ResolveID, but need it for URL building here too.
-		// Actually need to re-fetch config or trust ResolveID succeed meant config exists.
-		// Safer to re-fetch.
-		if profile == nil {
-			// Should not happen if ResolveID succeeded
-			return fmt.Errorf("active profile lost")
-		}
-
-		url := fmt.Sprintf("%s/api/datasources/%d", profile.URL, id)
-		req, err := http.NewRequest(http.MethodDelete, url, nil)
-		if err != nil {
-			return err
-		}
-		req.SetBasicAuth(profile.User, profile.Pass)
-
-		// Set Org ID header
-		activeOrg, _ := config.GetActiveOrg()
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
-		}
-
-		resp, err := http.DefaultClient.Do(req)
+		c, err := client.FromActive()
 		if err != nil {
 			return err
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("delete failed: %s %s", resp.Status, string(body))
+		if err := c.Delete(fmt.Sprintf("/api/datasources/%d", id), nil); err != nil {
+			return fmt.Errorf("delete failed: %w", err)
 		}
 
 		fmt.Printf("Data source deleted: %s (ID: %d)\n", idOrName, id)
@@ -204,40 +163,17 @@ var dsCreateCmd = &cobra.Command{
 			}
 		}
 
-		profile, err := config.GetActive()
-		if err != nil {
-			return err
-		}
-		if profile == nil {
-			return fmt.Errorf("no active profile set")
-		}
-
-		url := fmt.Sprintf("%s/api/datasources", profile.URL)
-		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
-		if err != nil {
-			return err
-		}
-		req.Header.Set("Content-Type", "application/json")
-		req.SetBasicAuth(profile.User, profile.Pass)
-
-		// Set Org ID header
-		activeOrg, _ := config.GetActiveOrg()
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
-		}
-
-		resp, err := http.DefaultClient.Do(req)
+		c, err := client.FromActive()
 		if err != nil {
 			return err
 		}
-		defer resp.Body.Close()
 
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("create failed: %s %s", resp.Status, string(body))
+		var resp client.Response
+		if err := c.Post("/api/datasources", payload, &resp); err != nil {
+			return fmt.Errorf("create failed: %w", err)
 		}
 
-		fmt.Printf("Status: %s\n%s\n", resp.Status, string(body))
+		fmt.Printf("Status: %s\n%s\n", resp.Status, string(resp.Body))
 		return nil
 	},
 }
@@ -249,44 +185,26 @@ var dsReadCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		idOrName := args[0]
-		id, err := datasource.ResolveID(idOrName)
+		printer, err := newPrinter(cmd, output.JSON)
 		if err != nil {
 			return err
 		}
-
-		profile, _ := config.GetActive() // Checked inside ResolveID
-
-		url := fmt.Sprintf("%s/api/datasources/%d", profile.URL, id)
-		req, err := http.NewRequest(http.MethodGet, url, nil)
+		id, err := datasource.ResolveID(idOrName)
 		if err != nil {
 			return err
 		}
-		req.SetBasicAuth(profile.User, profile.Pass)
-
-		// Set Org ID header
-		activeOrg, _ := config.GetActiveOrg()
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
-		}
 
-		resp, err := http.DefaultClient.Do(req)
+		c, err := client.FromActive()
 		if err != nil {
 			return err
 		}
-		defer resp.Body.Close()
 
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("read failed: %s %s", resp.Status, string(body))
+		var body []byte
+		if err := c.Get(fmt.Sprintf("/api/datasources/%d", id), &body); err != nil {
+			return fmt.Errorf("read failed: %w", err)
 		}
 
-		pretty, err := datasource.PrettyPrintJSON(body)
-		if err != nil {
-			fmt.Println(string(body))
-		} else {
-			fmt.Println(string(pretty))
-		}
-		return nil
+		return printer.Print(body, nil)
 	},
 }
 
@@ -347,31 +265,15 @@ var dsUpdateCmd = &cobra.Command{
 				return err
 			}
 
-			profile, _ := config.GetActive()
-
-			// Fetch current config
-			readURL := fmt.Sprintf("%s/api/datasources/%d", profile.URL, dsID)
-			req, err := http.NewRequest(http.MethodGet, readURL, nil)
-			if err != nil {
-				return err
-			}
-			req.SetBasicAuth(profile.User, profile.Pass)
-
-			// Set Org ID header
-			activeOrg, _ := config.GetActiveOrg()
-			if activeOrg != "" {
-				req.Header.Set("X-Grafana-Org-Id", activeOrg)
-			}
-
-			resp, err := http.DefaultClient.Do(req)
+			c, err := client.FromActive()
 			if err != nil {
 				return err
 			}
-			defer resp.Body.Close()
 
-			body, _ := io.ReadAll(resp.Body)
-			if resp.StatusCode != http.StatusOK {
-				return fmt.Errorf("failed to fetch current config: %s", resp.Status)
+			// Fetch current config
+			var body []byte
+			if err := c.Get(fmt.Sprintf("/api/datasources/%d", dsID), &body); err != nil {
+				return fmt.Errorf("failed to fetch current config: %w", err)
 			}
 
 			pretty, _ := datasource.PrettyPrintJSON(body)
@@ -411,34 +313,16 @@ var dsUpdateCmd = &cobra.Command{
 			}
 		}
 
-		profile, err := config.GetActive()
+		c, err := client.FromActive()
 		if err != nil {
 			return err
 		}
 
-		url := fmt.Sprintf("%s/api/datasources/%d", profile.URL, dsID)
-		req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(payload))
-		if err != nil {
+		var resp client.Response
+		if err := c.Call(http.MethodPut, fmt.Sprintf("/api/datasources/%d", dsID), payload, &resp); err != nil {
 			return err
 		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.SetBasicAuth(profile.User, profile.Pass)
-
-		// Set Org ID header
-		activeOrg, _ := config.GetActiveOrg()
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
-		}
-
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Status: %s\n%s\n", resp.Status, string(body))
+		fmt.Printf("Status: %s\n%s\n", resp.Status, string(resp.Body))
 
 		return nil
 	},
@@ -453,6 +337,7 @@ func init() {
 
 	// List command flags
 	dsListCmd.Flags().Bool("details", false, "Show detailed JSON output")
+	dsListCmd.Flags().MarkDeprecated("details", "use -o json instead")
 
 	// Create command flags
 	dsCreateCmd.Flags().String("file", "", "JSON file containing data source definition")
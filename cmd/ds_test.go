@@ -1,48 +1,48 @@
 package cmd
 
 import (
-	"bytes"
-	"fmt"
-	"gcli/internal/config"
-	"io"
-	"net/http"
-	"net/http/httptest"
-	"os"
-	"path/filepath"
+	"strings"
 	"testing"
+
+	"gcli/internal/grafanatest"
 )
 
 func TestDataSourceCommands(t *testing.T) {
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/api/datasources" {
-			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprintln(w, `[{"id":1, "name":"PromTest", "type":"prometheus", "url":"http://localhost:9090"}]`)
-		}
-	}))
-	defer ts.Close()
-
-	tmpDir, _ := os.MkdirTemp("", "gcli-ds-cmd-test-*")
-	defer os.RemoveAll(tmpDir)
-	tmpCfg := filepath.Join(tmpDir, "config.yaml")
-	os.Setenv("GCLI_CONFIG_PATH", tmpCfg)
-	defer os.Unsetenv("GCLI_CONFIG_PATH")
-
-	config.SaveProfile(config.Profile{Name: "test", URL: ts.URL, User: "a", Pass: "a"})
-	config.SetActive("test")
+	srv := useFakeGrafana(t)
+	srv.AddDatasource(grafanatest.MainOrgID, grafanatest.Datasource{UID: "prom", Name: "PromTest", Type: "prometheus", URL: "http://localhost:9090"})
 
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+	out, err := runCommand("ds", "list")
+	if err != nil {
+		t.Fatalf("ds list failed: %v", err)
+	}
+	if !strings.Contains(out, "PromTest") {
+		t.Errorf("expected 'PromTest' in output, got %s", out)
+	}
 
-	rootCmd.SetArgs([]string{"ds", "list"})
-	rootCmd.Execute()
-	w.Close()
-	os.Stdout = old
+	if _, err := runCommand("ds", "create", "--name", "Loki", "--type", "loki", "--url", "http://loki:3100"); err != nil {
+		t.Fatalf("ds create failed: %v", err)
+	}
+	out, err = runCommand("ds", "read", "Loki")
+	if err != nil {
+		t.Fatalf("ds read failed: %v", err)
+	}
+	if !strings.Contains(out, `"url": "http://loki:3100"`) {
+		t.Errorf("expected the created data source, got:\n%s", out)
+	}
 
-	var buf bytes.Buffer
-	io.Copy(&buf, r)
+	// Creating the same name again is a conflict.
+	if _, err := runCommand("ds", "create", "--name", "Loki", "--type", "loki", "--url", "http://loki:3100"); exitCode(err) != exitConflict {
+		t.Errorf("expected exit code %d for a duplicate name, got %d (%v)", exitConflict, exitCode(err), err)
+	}
 
-	if !bytes.Contains(buf.Bytes(), []byte("PromTest")) {
-		t.Errorf("expected 'PromTest' in output, got %s", buf.String())
+	if _, err := runCommand("ds", "rm", "PromTest"); err != nil {
+		t.Fatalf("ds rm failed: %v", err)
+	}
+	var names []string
+	for _, ds := range srv.Datasources(grafanatest.MainOrgID) {
+		names = append(names, ds["name"].(string))
+	}
+	if strings.Join(names, ",") != "Loki" {
+		t.Errorf("expected only Loki to remain, got %v", names)
 	}
 }
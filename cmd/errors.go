@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"gcli/internal/client"
+)
+
+// Exit codes, documented in docs/Usage-Examples.md.
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitUnauthorized = 3
+	exitForbidden    = 4
+	exitNotFound     = 5
+	exitConflict     = 6
+	exitValidation   = 7
+	exitNetwork      = 8
+)
+
+// exitCoder is implemented by errors that choose their own exit code.
+type exitCoder interface {
+	ExitCode() int
+}
+
+// usageError reports invalid flags or arguments.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+func (e *usageError) ExitCode() int { return exitUsage }
+
+// silentError exits with a status without printing an error, for commands
+// that report their outcome on stdout, such as dash diff.
+type silentError struct {
+	code int
+}
+
+func (e *silentError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+func (e *silentError) ExitCode() int { return e.code }
+
+// comparisonError reports a failure of a command whose exit status 1 means
+// differences were found. Like diff(1), errors that would exit 1 exit 2
+// instead; more specific codes are kept.
+type comparisonError struct {
+	err error
+}
+
+func (e *comparisonError) Error() string { return e.err.Error() }
+func (e *comparisonError) Unwrap() error { return e.err }
+func (e *comparisonError) ExitCode() int {
+	if code := exitCode(e.err); code != exitError {
+		return code
+	}
+	return exitUsage
+}
+
+// exitCode maps err to the process exit status.
+func exitCode(err error) int {
+	// Requests withheld by --as-curl or --dry-run are not failures.
+	if err == nil || errors.Is(err, client.ErrCurlOnly) || errors.Is(err, client.ErrDryRun) {
+		return exitOK
+	}
+	var ec exitCoder
+	if errors.As(err, &ec) {
+		return ec.ExitCode()
+	}
+	switch {
+	case errors.Is(err, client.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, client.ErrForbidden):
+		return exitForbidden
+	case errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrConflict):
+		return exitConflict
+	case errors.Is(err, client.ErrValidation):
+		return exitValidation
+	case errors.Is(err, client.ErrNetwork):
+		return exitNetwork
+	}
+	return exitError
+}
@@ -1,13 +1,14 @@
 package cmd
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
 
+	"gcli/internal/client"
 	"gcli/internal/config"
+	"gcli/internal/output"
 
 	"github.com/spf13/cobra"
 )
@@ -22,35 +23,18 @@ var orgListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List organizations for the active profile",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		profile, err := config.GetActive()
+		printer, err := newPrinter(cmd, output.Table)
 		if err != nil {
 			return err
 		}
-		if profile == nil {
-			return fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
-		}
-		url := fmt.Sprintf("%s/api/orgs", profile.URL)
-		req, err := http.NewRequest(http.MethodGet, url, nil)
+		c, err := newOrgClient()
 		if err != nil {
 			return err
 		}
-		req.SetBasicAuth(profile.User, profile.Pass)
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-
-		details, _ := cmd.Flags().GetBool("details")
-		if details {
-			var pretty bytes.Buffer
-			if err := json.Indent(&pretty, body, "", "  "); err != nil {
-				fmt.Printf("Status: %s\n%s\n", resp.Status, string(body))
-			} else {
-				fmt.Printf("Status: %s\n%s\n", resp.Status, pretty.String())
-			}
-			return nil
+		limit, _ := cmd.Flags().GetInt("limit")
+		var body []byte
+		if err := c.GetAll("/api/orgs", limit, &body); err != nil {
+			return fmt.Errorf("list failed: %w", err)
 		}
 
 		// Tabular output
@@ -62,12 +46,20 @@ var orgListCmd = &cobra.Command{
 			return fmt.Errorf("failed to parse response: %w", err)
 		}
 
-		fmt.Printf("%-5s %s\n", "ID", "Name")
-		fmt.Println("----------------------------------------")
+		activeOrg, _ := config.GetActiveOrg()
+		table := &output.TableData{Columns: []output.Column{
+			{Header: "ID"},
+			{Header: "Name"},
+			{Header: "Active", Wide: true},
+		}}
 		for _, org := range orgs {
-			fmt.Printf("%-5d %s\n", org.ID, org.Name)
+			active := ""
+			if strconv.Itoa(org.ID) == activeOrg {
+				active = "*"
+			}
+			table.AddRow(strconv.Itoa(org.ID), org.Name, active)
 		}
-		return nil
+		return printer.Print(body, table)
 	},
 }
 
@@ -78,48 +70,14 @@ var orgUseCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		orgIDOrName := args[0]
-		profile, err := config.GetActive()
-		if err != nil {
-			return err
-		}
-		if profile == nil {
-			return fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
-		}
-		// Fetch organization list
-		url := fmt.Sprintf("%s/api/orgs", profile.URL)
-		req, err := http.NewRequest(http.MethodGet, url, nil)
+		c, err := newOrgClient()
 		if err != nil {
 			return err
 		}
-		req.SetBasicAuth(profile.User, profile.Pass)
-		resp, err := http.DefaultClient.Do(req)
+		resolvedID, err := resolveOrgID(c, orgIDOrName)
 		if err != nil {
 			return err
 		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to fetch org list: %s", resp.Status)
-		}
-		body, _ := io.ReadAll(resp.Body)
-		var orgs []struct {
-			ID   int    `json:"id"`
-			Name string `json:"name"`
-		}
-		if err := json.Unmarshal(body, &orgs); err != nil {
-			return fmt.Errorf("failed to parse org list: %w", err)
-		}
-		found := false
-		var resolvedID int
-		for _, o := range orgs {
-			if fmt.Sprintf("%d", o.ID) == orgIDOrName || o.Name == orgIDOrName {
-				found = true
-				resolvedID = o.ID
-				break
-			}
-		}
-		if !found {
-			return fmt.Errorf("organization %s not found", orgIDOrName)
-		}
 		if err := config.SetActiveOrg(fmt.Sprintf("%d", resolvedID)); err != nil {
 			return err
 		}
@@ -137,29 +95,15 @@ var orgCreateCmd = &cobra.Command{
 		if name == "" {
 			return fmt.Errorf("--name flag is required")
 		}
-		// Build JSON payload
-		payload := fmt.Sprintf(`{"name":"%s"}`, name)
-		profile, err := config.GetActive()
+		c, err := newOrgClient()
 		if err != nil {
 			return err
 		}
-		if profile == nil {
-			return fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
-		}
-		url := fmt.Sprintf("%s/api/orgs", profile.URL)
-		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(payload)))
-		if err != nil {
-			return err
-		}
-		req.Header.Set("Content-Type", "application/json")
-		req.SetBasicAuth(profile.User, profile.Pass)
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
+		var resp client.Response
+		if err := c.Call(http.MethodPost, "/api/orgs", map[string]string{"name": name}, &resp); err != nil {
 			return err
 		}
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Status: %s\n%s\n", resp.Status, string(body))
+		fmt.Printf("Status: %s\n%s\n", resp.Status, string(resp.Body))
 		return nil
 	},
 }
@@ -171,62 +115,21 @@ var orgRmCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		orgIDOrName := args[0]
-		profile, err := config.GetActive()
+		c, err := newOrgClient()
 		if err != nil {
 			return err
 		}
-		if profile == nil {
-			return fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
-		}
 		// Fetch organization list to verify existence
-		listURL := fmt.Sprintf("%s/api/orgs", profile.URL)
-		listReq, err := http.NewRequest(http.MethodGet, listURL, nil)
+		orgID, err := resolveOrgID(c, orgIDOrName)
 		if err != nil {
 			return err
 		}
-		listReq.SetBasicAuth(profile.User, profile.Pass)
-		listResp, err := http.DefaultClient.Do(listReq)
-		if err != nil {
-			return err
-		}
-		defer listResp.Body.Close()
-		if listResp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to fetch org list: %s", listResp.Status)
-		}
-		listBody, _ := io.ReadAll(listResp.Body)
-		var orgs []struct {
-			ID   int    `json:"id"`
-			Name string `json:"name"`
-		}
-		if err := json.Unmarshal(listBody, &orgs); err != nil {
-			return fmt.Errorf("failed to parse org list: %w", err)
-		}
-		found := false
-		var orgID string
-		for _, o := range orgs {
-			if fmt.Sprintf("%d", o.ID) == orgIDOrName || o.Name == orgIDOrName {
-				found = true
-				orgID = fmt.Sprintf("%d", o.ID)
-				break
-			}
-		}
-		if !found {
-			return fmt.Errorf("organization %s not found", orgIDOrName)
-		}
 		// Proceed to delete using the numeric ID
-		url := fmt.Sprintf("%s/api/orgs/%s", profile.URL, orgID)
-		req, err := http.NewRequest(http.MethodDelete, url, nil)
-		if err != nil {
+		var resp client.Response
+		if err := c.Call(http.MethodDelete, fmt.Sprintf("/api/orgs/%d", orgID), nil, &resp); err != nil {
 			return err
 		}
-		req.SetBasicAuth(profile.User, profile.Pass)
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Status: %s\n%s\n", resp.Status, string(body))
+		fmt.Printf("Status: %s\n%s\n", resp.Status, string(resp.Body))
 		return nil
 	},
 }
@@ -243,78 +146,56 @@ var orgUpdateCmd = &cobra.Command{
 			return fmt.Errorf("--name flag is required")
 		}
 
-		profile, err := config.GetActive()
+		c, err := newOrgClient()
 		if err != nil {
 			return err
 		}
-		if profile == nil {
-			return fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
-		}
 
 		// Fetch organization list to verify existence and get ID
-		listURL := fmt.Sprintf("%s/api/orgs", profile.URL)
-		listReq, err := http.NewRequest(http.MethodGet, listURL, nil)
+		orgID, err := resolveOrgID(c, orgIDOrName)
 		if err != nil {
 			return err
 		}
-		listReq.SetBasicAuth(profile.User, profile.Pass)
-		listResp, err := http.DefaultClient.Do(listReq)
-		if err != nil {
-			return err
-		}
-		defer listResp.Body.Close()
-		if listResp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to fetch org list: %s", listResp.Status)
-		}
-
-		listBody, _ := io.ReadAll(listResp.Body)
-		var orgs []struct {
-			ID   int    `json:"id"`
-			Name string `json:"name"`
-		}
-		if err := json.Unmarshal(listBody, &orgs); err != nil {
-			return fmt.Errorf("failed to parse org list: %w", err)
-		}
-
-		found := false
-		var orgID string
-		for _, o := range orgs {
-			if fmt.Sprintf("%d", o.ID) == orgIDOrName || o.Name == orgIDOrName {
-				found = true
-				orgID = fmt.Sprintf("%d", o.ID)
-				break
-			}
-		}
-		if !found {
-			return fmt.Errorf("organization %s not found", orgIDOrName)
-		}
 
 		// Proceed to update
-		url := fmt.Sprintf("%s/api/orgs/%s", profile.URL, orgID)
-		payload := fmt.Sprintf(`{"name":"%s"}`, newName)
-		req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader([]byte(payload)))
-		if err != nil {
-			return err
+		payload := map[string]string{"name": newName}
+		if err := c.Put(fmt.Sprintf("/api/orgs/%d", orgID), payload, nil); err != nil {
+			return fmt.Errorf("update failed: %w", err)
 		}
-		req.Header.Set("Content-Type", "application/json")
-		req.SetBasicAuth(profile.User, profile.Pass)
 
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("update failed: %s %s", resp.Status, string(body))
-		}
-
-		fmt.Printf("Organization updated: %s -> %s (ID: %s)\n", orgIDOrName, newName, orgID)
+		fmt.Printf("Organization updated: %s -> %s (ID: %d)\n", orgIDOrName, newName, orgID)
 		return nil
 	},
 }
 
+// newOrgClient returns a client for the active profile without the
+// organization header, since the /api/orgs endpoints are not org-scoped.
+func newOrgClient() (*client.Client, error) {
+	c, err := client.FromActive()
+	if err != nil {
+		return nil, err
+	}
+	c.OrgID = ""
+	return c, nil
+}
+
+// resolveOrgID looks up an organization by ID or name and returns its ID.
+func resolveOrgID(c *client.Client, orgIDOrName string) (int, error) {
+	var orgs []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := c.GetAll("/api/orgs", 0, &orgs); err != nil {
+		return 0, fmt.Errorf("failed to fetch org list: %w", err)
+	}
+	for _, o := range orgs {
+		if fmt.Sprintf("%d", o.ID) == orgIDOrName || o.Name == orgIDOrName {
+			return o.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("organization %s not found", orgIDOrName)
+}
+
 func init() {
 	// Register org subcommands
 	orgCmd.AddCommand(orgListCmd)
@@ -332,5 +213,7 @@ func init() {
 	orgUpdateCmd.MarkFlagRequired("name")
 
 	// List command flags
+	orgListCmd.Flags().Int("limit", 0, "Maximum number of organizations to list (default all)")
 	orgListCmd.Flags().Bool("details", false, "Show detailed JSON output")
+	orgListCmd.Flags().MarkDeprecated("details", "use -o json instead")
 }
@@ -1,66 +1,75 @@
 package cmd
 
 import (
-	"bytes"
 	"fmt"
-	"gcli/internal/config"
-	"io"
-	"net/http"
-	"net/http/httptest"
-	"os"
-	"path/filepath"
+	"strings"
 	"testing"
+
+	"gcli/internal/grafanatest"
 )
 
 func TestOrgCommands(t *testing.T) {
-	// Mock Grafana API
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			if r.URL.Path == "/api/orgs" {
-				fmt.Fprintln(w, `[{"id":1, "name":"Main Org."}]`)
-			}
-		case http.MethodPost:
-			if r.URL.Path == "/api/orgs" {
-				w.WriteHeader(http.StatusOK)
-				fmt.Fprintln(w, `{"message":"Organization created", "orgId":2}`)
-			}
-		}
-	}))
-	defer ts.Close()
+	srv := useFakeGrafana(t)
 
-	// Setup temp config
-	tmpDir, _ := os.MkdirTemp("", "gcli-org-test-*")
-	defer os.RemoveAll(tmpDir)
-	tmpCfg := filepath.Join(tmpDir, "config.yaml")
-	os.Setenv("GCLI_CONFIG_PATH", tmpCfg)
-	defer os.Unsetenv("GCLI_CONFIG_PATH")
+	// Test 'org list'
+	out, err := runCommand("org", "list")
+	if err != nil {
+		t.Fatalf("org list failed: %v", err)
+	}
+	if !strings.Contains(out, "Main Org.") {
+		t.Errorf("expected 'Main Org.' in output, got %s", out)
+	}
 
-	config.SaveProfile(config.Profile{
-		Name: "test",
-		URL:  ts.URL,
-		User: "admin",
-		Pass: "admin",
-	})
-	config.SetActive("test")
+	if _, err := runCommand("org", "create", "--name", "Staging"); err != nil {
+		t.Fatalf("org create failed: %v", err)
+	}
+	out, _ = runCommand("org", "list")
+	if !strings.Contains(out, "Staging") {
+		t.Errorf("expected 'Staging' in output, got %s", out)
+	}
 
-	// Test 'org list'
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+	// Data sources are scoped to the active organization.
+	srv.AddDatasource(grafanatest.MainOrgID, grafanatest.Datasource{Name: "MainProm", Type: "prometheus"})
+	if _, err := runCommand("org", "use", "Staging"); err != nil {
+		t.Fatalf("org use failed: %v", err)
+	}
+	out, err = runCommand("ds", "list")
+	if err != nil {
+		t.Fatalf("ds list failed: %v", err)
+	}
+	if strings.Contains(out, "MainProm") {
+		t.Errorf("expected no data sources of Main Org. in Staging, got %s", out)
+	}
 
-	rootCmd.SetArgs([]string{"org", "list"})
-	err := rootCmd.Execute()
-	w.Close()
-	os.Stdout = old
+	if _, err := runCommand("org", "rm", "Staging"); err != nil {
+		t.Fatalf("org rm failed: %v", err)
+	}
+	if _, err := runCommand("ds", "list"); exitCode(err) != exitForbidden {
+		t.Errorf("expected exit code %d for a deleted active org, got %d (%v)", exitForbidden, exitCode(err), err)
+	}
+}
 
-	var buf bytes.Buffer
-	io.Copy(&buf, r)
+func TestOrgLookupPaginates(t *testing.T) {
+	srv := useFakeGrafana(t)
+	// With Main Org. the last one lands on the second page of /api/orgs.
+	var last int64
+	for i := 1; i <= 1000; i++ {
+		last = srv.AddOrg(fmt.Sprintf("Team %04d", i))
+	}
 
-	if err != nil {
-		t.Fatalf("org list failed: %v", err)
+	if _, err := runCommand("org", "use", "Team 1000"); err != nil {
+		t.Fatalf("org use of an org on the second page failed: %v", err)
+	}
+	if _, err := runCommand("org", "update", "Team 1000", "--name", "Team Last"); err != nil {
+		t.Fatalf("org update failed: %v", err)
 	}
-	if !bytes.Contains(buf.Bytes(), []byte("Main Org.")) {
-		t.Errorf("expected 'Main Org.' in output, got %s", buf.String())
+	if _, err := runCommand("org", "rm", "Team Last"); err != nil {
+		t.Fatalf("org rm failed: %v", err)
+	}
+	for _, req := range srv.Requests() {
+		if req == fmt.Sprintf("DELETE /api/orgs/%d", last) {
+			return
+		}
 	}
+	t.Errorf("expected org %d to be deleted", last)
 }
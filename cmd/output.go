@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"os"
+
+	"gcli/internal/output"
+
+	"github.com/spf13/cobra"
+)
+
+// newPrinter returns a printer for the --output flag, or for def when the
+// flag is not set, with the table options from --columns, --sort-by and
+// --no-headers. The deprecated --details flag selects json.
+func newPrinter(cmd *cobra.Command, def string) (*output.Printer, error) {
+	format, _ := cmd.Flags().GetString("output")
+	if format == "" {
+		if f := cmd.Flags().Lookup("details"); f != nil && f.Changed {
+			format = output.JSON
+		}
+	}
+	if format == "" {
+		format = def
+	}
+	p, err := output.New(format, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	p.Columns, _ = cmd.Flags().GetStringSlice("columns")
+	p.SortBy, _ = cmd.Flags().GetString("sort-by")
+	p.NoHeaders, _ = cmd.Flags().GetBool("no-headers")
+	return p, nil
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import "os"
+
+// stdinIsTerminal reports whether stdin is an interactive terminal, so
+// commands only prompt when someone can answer. The null device is a
+// character device too, but nobody is there to answer. Tests replace it to
+// exercise prompts.
+var stdinIsTerminal = func() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(fi, null)
+}
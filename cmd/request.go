@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"sort"
 	"strings"
 
-	"gcli/internal/config"
+	"gcli/internal/client"
+	"gcli/internal/output"
 
 	"github.com/spf13/cobra"
 )
@@ -14,51 +20,190 @@ import (
 var requestCmd = &cobra.Command{
 	Use:   "request [METHOD] [PATH]",
 	Short: "Make a request to the selected Grafana instance",
-	Args:  cobra.MinimumNArgs(2),
+	Long: `Make a request to the selected Grafana instance.
+
+The response body is printed to stdout, JSON pretty-printed unless --raw is
+given. A 4xx or 5xx response exits with a non-zero status unless --no-fail is
+given.`,
+	Example: `  gcli request GET /api/search --query type=dash-db --query query=cpu
+  gcli request POST /api/folders --data '{"title":"Team A"}'
+  gcli request PUT /api/dashboards/db --data-file dashboard.json
+  cat payload.json | gcli request POST /api/annotations --data-file -
+  gcli request GET /api/health --include
+  gcli request GET /api/users/search --paginate`,
+	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		method := strings.ToUpper(args[0])
 		path := args[1]
-		// Ensure path starts with a slash
-		if !strings.HasPrefix(path, "/") {
-			path = "/" + path
+		raw, _ := cmd.Flags().GetBool("raw")
+		include, _ := cmd.Flags().GetBool("include")
+		noFail, _ := cmd.Flags().GetBool("no-fail")
+		paginate, _ := cmd.Flags().GetBool("paginate")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		// Without -o the body is printed as received, or pretty-printed.
+		var printer *output.Printer
+		if format, _ := cmd.Flags().GetString("output"); format != "" {
+			var err error
+			if printer, err = newPrinter(cmd, ""); err != nil {
+				return err
+			}
 		}
-		profile, err := config.GetActive()
+
+		headerFlags, _ := cmd.Flags().GetStringArray("header")
+		headers, err := parseHeaders(headerFlags)
 		if err != nil {
 			return err
 		}
-		if profile == nil {
-			return fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
+		queryFlags, _ := cmd.Flags().GetStringArray("query")
+		path, err = addQuery(path, queryFlags)
+		if err != nil {
+			return err
 		}
-		url := strings.TrimRight(profile.URL, "/") + path
-		req, err := http.NewRequest(method, url, nil)
+		body, err := requestBody(cmd)
 		if err != nil {
 			return err
 		}
-		req.SetBasicAuth(profile.User, profile.Pass)
 
-		// Set Org ID header if active org is selected
-		activeOrg, _ := config.GetActiveOrg()
-		if activeOrg != "" {
-			req.Header.Set("X-Grafana-Org-Id", activeOrg)
+		c, err := client.FromActive()
+		if err != nil {
+			return err
 		}
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		if paginate {
+			if method != http.MethodGet {
+				return &usageError{fmt.Errorf("--paginate only works with GET")}
+			}
+			// Each page is a separate request carrying the extra headers.
+			merged := make(map[string]string, len(c.Headers)+len(headers))
+			for name, value := range c.Headers {
+				merged[name] = value
+			}
+			for name, value := range headers {
+				merged[name] = value
+			}
+			c.Headers = merged
+			var items []byte
+			if err := c.GetAll(path, limit, &items); err != nil {
+				return err
+			}
+			if printer != nil {
+				return printer.Print(items, nil)
+			}
+			printBody(items, raw)
+			return nil
+		}
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := c.NewRequest(method, path, reader)
 		if err != nil {
 			return err
 		}
-		defer resp.Body.Close()
-		fmt.Printf("Status: %s\n", resp.Status)
-		body, err := io.ReadAll(resp.Body)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := c.SendRequest(req)
 		if err != nil {
 			return err
 		}
-		fmt.Println(string(body))
-		return nil
+
+		if include {
+			printResponseHeaders(resp)
+		}
+		apiErr := resp.Check(method, path)
+		switch {
+		case printer != nil && apiErr == nil:
+			if err := printer.Print(resp.Body, nil); err != nil {
+				return err
+			}
+		default:
+			printBody(resp.Body, raw)
+		}
+
+		if noFail {
+			return nil
+		}
+		return apiErr
 	},
 }
 
+// printBody prints a response body, pretty-printing JSON unless raw is set.
+func printBody(body []byte, raw bool) {
+	if raw || !json.Valid(body) {
+		os.Stdout.Write(body)
+		if len(body) > 0 && !bytes.HasSuffix(body, []byte("\n")) {
+			fmt.Println()
+		}
+		return
+	}
+	var pretty bytes.Buffer
+	json.Indent(&pretty, body, "", "  ")
+	fmt.Println(pretty.String())
+}
+
+// requestBody returns the body given with --data or --data-file, or nil.
+func requestBody(cmd *cobra.Command) ([]byte, error) {
+	data, _ := cmd.Flags().GetString("data")
+	dataFile, _ := cmd.Flags().GetString("data-file")
+	switch {
+	case cmd.Flags().Changed("data") && dataFile != "":
+		return nil, &usageError{fmt.Errorf("--data and --data-file cannot be combined")}
+	case cmd.Flags().Changed("data"):
+		return []byte(data), nil
+	case dataFile == "-":
+		return io.ReadAll(os.Stdin)
+	case dataFile != "":
+		return os.ReadFile(dataFile)
+	}
+	return nil, nil
+}
+
+// addQuery appends key=value parameters to the query string of path.
+func addQuery(path string, params []string) (string, error) {
+	if len(params) == 0 {
+		return path, nil
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	q := u.Query()
+	for _, p := range params {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok || key == "" {
+			return "", &usageError{fmt.Errorf("invalid query parameter %q, expected key=value", p)}
+		}
+		q.Add(key, value)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func printResponseHeaders(resp *client.Response) {
+	fmt.Printf("HTTP %s\n", resp.Status)
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range resp.Header[name] {
+			fmt.Printf("%s: %s\n", name, v)
+		}
+	}
+	fmt.Println()
+}
+
 func init() {
 	// requestCmd is added to root in root.go's init()
-	// No extra flags needed for now.
+	requestCmd.Flags().String("data", "", "Request body")
+	requestCmd.Flags().String("data-file", "", "File with the request body, or - for stdin")
+	requestCmd.Flags().StringArrayP("header", "H", nil, "Extra request header as 'Name: value' (repeatable)")
+	requestCmd.Flags().StringArray("query", nil, "Query parameter as key=value (repeatable)")
+	requestCmd.Flags().Bool("raw", false, "Print the response body as received instead of pretty-printing JSON")
+	requestCmd.Flags().BoolP("include", "i", false, "Print the response status and headers before the body")
+	requestCmd.Flags().Bool("paginate", false, "Fetch all pages of a paginated GET endpoint and print their items as one JSON array")
+	requestCmd.Flags().Int("limit", 0, "With --paginate, the maximum number of items (default all)")
+	requestCmd.Flags().Bool("no-fail", false, "Exit with status 0 even on 4xx and 5xx responses")
 }
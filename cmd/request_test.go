@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gcli/internal/config"
+)
+
+func TestRequestCommand(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Seen-Header", r.Header.Get("X-Custom"))
+		switch r.URL.Path {
+		case "/api/teams/search":
+			if r.URL.Query().Get("page") == "1" {
+				fmt.Fprint(w, `{"totalCount":2,"teams":[{"name":"a"}]}`)
+			} else {
+				fmt.Fprint(w, `{"totalCount":2,"teams":[{"name":"b"}]}`)
+			}
+		case "/api/echo":
+			fmt.Fprintf(w, `{"method":%q,"query":%q,"contentType":%q,"body":%q}`,
+				r.Method, r.URL.RawQuery, r.Header.Get("Content-Type"), string(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	os.Setenv("GCLI_CONFIG_PATH", filepath.Join(t.TempDir(), "config.yaml"))
+	defer os.Unsetenv("GCLI_CONFIG_PATH")
+	config.SaveProfile(config.Profile{Name: "test", URL: ts.URL, Token: "t"})
+	config.SetActive("test")
+
+	run := func(stdin string, args ...string) (string, int) {
+		oldIn := os.Stdin
+		inR, inW, _ := os.Pipe()
+		inW.WriteString(stdin)
+		inW.Close()
+		os.Stdin = inR
+		defer func() { os.Stdin = oldIn }()
+
+		out, err := runCommand(append([]string{"request"}, args...)...)
+		return out, exitCode(err)
+	}
+
+	out, code := run(`{"title":"from stdin"}`, "post", "/api/echo", "--data-file", "-",
+		"-H", "X-Custom: yes", "-H", "Content-Type: application/vnd.test", "--query", "a=1", "--query", "b=x y", "--include")
+	if code != exitOK {
+		t.Fatalf("expected success, got exit code %d: %s", code, out)
+	}
+	for _, want := range []string{
+		"HTTP 200 OK\n", "X-Seen-Header: yes\n",
+		`"method": "POST"`, `"query": "a=1&b=x+y"`, `"contentType": "application/vnd.test"`,
+		`"body": "{\"title\":\"from stdin\"}"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Status:") {
+		t.Errorf("did not expect a Status: line, got:\n%s", out)
+	}
+
+	out, code = run("", "PUT", "/api/echo", "--data", `{"a":1}`, "--raw")
+	if code != exitOK || !strings.HasPrefix(out, `{"method":"PUT"`) || !strings.Contains(out, `application/json`) {
+		t.Errorf("expected raw JSON output, got %d:\n%s", code, out)
+	}
+
+	out, code = run("", "GET", "/api/missing")
+	if code != exitNotFound || !strings.Contains(out, `"message": "Not found"`) {
+		t.Errorf("expected exit code %d and the error body, got %d:\n%s", exitNotFound, code, out)
+	}
+	if _, code = run("", "GET", "/api/missing", "--no-fail"); code != exitOK {
+		t.Errorf("expected exit code 0 with --no-fail, got %d", code)
+	}
+	out, code = run("", "GET", "/api/teams/search", "--query", "perpage=1", "--paginate", "--raw")
+	if code != exitOK || out != `[{"name":"a"},{"name":"b"}]`+"\n" {
+		t.Errorf("expected concatenated pages, got %d: %q", code, out)
+	}
+
+	if _, code = run("", "GET", "/api/echo", "--query", "novalue"); code != exitUsage {
+		t.Errorf("expected usage error for a malformed --query, got %d", code)
+	}
+}
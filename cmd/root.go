@@ -1,7 +1,15 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gcli/internal/client"
+	"gcli/internal/config"
+	"gcli/internal/output"
 
 	"github.com/spf13/cobra"
 )
@@ -10,24 +18,116 @@ var rootCmd = &cobra.Command{
 	Use:   "gcli",
 	Short: "CLI tool to interact with Grafana API",
 	Long:  `gcli provides commands to manage Grafana API configurations and make requests.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Cobra checks required flags only after this hook, and reports
+		// them as plain errors.
+		if err := cmd.ValidateRequiredFlags(); err != nil {
+			return &usageError{err}
+		}
+
+		// Flags win over the GCLI_* environment variables, which are read
+		// by the config package itself. Read them from the root so local
+		// flags like `config add --url` do not shadow them.
+		profile, _ := cmd.Root().PersistentFlags().GetString("profile")
+		org, _ := cmd.Root().PersistentFlags().GetString("org")
+		url, _ := cmd.Root().PersistentFlags().GetString("url")
+		token, _ := cmd.Root().PersistentFlags().GetString("token")
+		config.SetOverrides(config.Overrides{
+			Profile: profile,
+			Org:     org,
+			URL:     url,
+			Token:   token,
+		})
+
+		verbose, _ := cmd.Root().PersistentFlags().GetCount("verbose")
+		asCurl, _ := cmd.Root().PersistentFlags().GetString("as-curl")
+		if asCurl != "" && asCurl != client.CurlOnly && asCurl != client.CurlRun {
+			return &usageError{fmt.Errorf("invalid --as-curl value %q (expected %s or %s)", asCurl, client.CurlOnly, client.CurlRun)}
+		}
+		client.SetTrace(client.TraceOptions{Verbose: verbose, Curl: asCurl})
+		dryRun, _ := cmd.Root().PersistentFlags().GetBool("dry-run")
+		client.SetDryRun(dryRun)
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("gcli: use subcommands like config or request")
 	},
 }
 
-// Execute runs the root command.
+// Execute runs the root command and exits with a non-zero status on error;
+// see exitCode.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		// In a real CLI you might os.Exit(1)
+	c, err := executeRoot()
+	if exitCode(err) == exitOK {
+		return
+	}
+	var silent *silentError
+	if errors.As(err, &silent) {
+		os.Exit(silent.code)
+	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", c.CommandPath())
+	}
+	os.Exit(exitCode(err))
+}
+
+// executeRoot runs the root command, reporting argument errors of any
+// command as usage errors.
+func executeRoot() (*cobra.Command, error) {
+	wrapArgsOnce.Do(func() { wrapArgs(rootCmd) })
+	return rootCmd.ExecuteC()
+}
+
+var wrapArgsOnce sync.Once
+
+// wrapArgs makes the Args validators of c and its subcommands return
+// usageError, like flag errors.
+func wrapArgs(c *cobra.Command) {
+	if validate := c.Args; validate != nil {
+		c.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validate(cmd, args); err != nil {
+				return &usageError{err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range c.Commands() {
+		wrapArgs(sub)
 	}
 }
 
 func init() {
+	// Execute reports errors itself, on stderr and through the exit code.
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return &usageError{err}
+	})
+
 	// Add subcommands defined in other files.
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(orgCmd)
 	rootCmd.AddCommand(dsCmd)
 	rootCmd.AddCommand(dashCmd)
 	rootCmd.AddCommand(requestCmd)
+
+	// Per-invocation target selection; see also GCLI_PROFILE, GCLI_ORG,
+	// GCLI_URL and GCLI_TOKEN.
+	rootCmd.PersistentFlags().String("profile", "", "Profile to use instead of the active one (env GCLI_PROFILE)")
+	rootCmd.PersistentFlags().String("org", "", "Organization ID to use instead of the active one (env GCLI_ORG)")
+	rootCmd.PersistentFlags().String("url", "", "Grafana base URL overriding the profile (env GCLI_URL)")
+	rootCmd.PersistentFlags().String("token", "", "Bearer token overriding the profile credentials (env GCLI_TOKEN)")
+
+	rootCmd.PersistentFlags().StringP("output", "o", "", "Output format: "+strings.Join(output.Formats, ", ")+", or "+strings.Join(output.TemplateFormats, "|")+"=TEMPLATE")
+	rootCmd.PersistentFlags().CountP("verbose", "v", "Log requests to stderr; repeat for headers (-vv) and bodies (-vvv)")
+	rootCmd.PersistentFlags().String("as-curl", "", "Print requests as curl commands and send only reads; --as-curl=run sends writes too")
+	rootCmd.PersistentFlags().Lookup("as-curl").NoOptDefVal = client.CurlOnly
+
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Send reads but only print the write requests that would be sent")
+
+	rootCmd.PersistentFlags().StringSlice("columns", nil, "Comma-separated table columns to show, in order (table, wide and csv)")
+	rootCmd.PersistentFlags().String("sort-by", "", "Sort table rows by a column; prefix with - for descending order")
+	rootCmd.PersistentFlags().Bool("no-headers", false, "Omit the header row in table and csv output")
 }
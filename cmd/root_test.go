@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gcli/internal/config"
+	"gcli/internal/grafanatest"
+
+	"github.com/spf13/pflag"
+)
+
+func TestExitCodes(t *testing.T) {
+	srv := useFakeGrafana(t)
+	config.SaveProfile(config.Profile{Name: "bad-token", URL: srv.URL, Token: "wrong"})
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"dash", "read", "missing"}, exitNotFound},
+		{[]string{"org", "create", "--name", "Main Org."}, exitConflict},
+		{[]string{"ds", "list", "--profile", "bad-token"}, exitUnauthorized},
+		{[]string{"ds", "list", "--org", "99"}, exitForbidden},
+		{[]string{"ds", "list", "--no-such-flag"}, exitUsage},
+		{[]string{"dash", "read"}, exitUsage},
+		{[]string{"dash", "versions", "diff", "cpu", "1"}, exitUsage},
+		{[]string{"dash", "pull", "extra"}, exitUsage},
+		{[]string{"org", "create"}, exitUsage},
+		{[]string{"dash", "create"}, exitUsage},
+	}
+	for _, tt := range tests {
+		_, err := runCommand(tt.args...)
+		if got := exitCode(err); got != tt.code {
+			t.Errorf("%v: expected exit code %d, got %d (%v)", tt.args, tt.code, got, err)
+		}
+	}
+
+	if got := exitCode(errors.New("plain")); got != exitError {
+		t.Errorf("expected exit code %d for plain errors, got %d", exitError, got)
+	}
+	if got := exitCode(nil); got != exitOK {
+		t.Errorf("expected exit code 0 for nil, got %d", got)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	srv := useFakeGrafana(t)
+	id := srv.AddDatasource(grafanatest.MainOrgID, grafanatest.Datasource{UID: "prom", Name: "PromTest", Type: "prometheus"})
+
+	var out strings.Builder
+	for _, args := range [][]string{
+		{"ds", "rm", "PromTest", "--dry-run"},
+		{"org", "create", "--name", "Staging", "--dry-run"},
+	} {
+		o, err := runCommand(args...)
+		if code := exitCode(err); code != exitOK {
+			t.Errorf("%v: expected exit code 0, got %d (%v)", args, code, err)
+		}
+		out.WriteString(o)
+	}
+
+	for _, req := range srv.Requests() {
+		if !strings.HasPrefix(req, "GET ") {
+			t.Errorf("expected no write requests, got %s", req)
+		}
+	}
+	for _, want := range []string{fmt.Sprintf("DRY RUN: DELETE /api/datasources/%d", id), "DRY RUN: POST /api/orgs", `"name": "Staging"`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestAsCurl(t *testing.T) {
+	srv := useFakeGrafana(t)
+	id := srv.AddDatasource(grafanatest.MainOrgID, grafanatest.Datasource{UID: "prom", Name: "PromTest", Type: "prometheus"})
+
+	// The lookup is sent so the DELETE it leads to can be printed.
+	out, err := runCommand("ds", "rm", "PromTest", "--as-curl")
+	if code := exitCode(err); code != exitOK {
+		t.Errorf("expected exit code 0, got %d (%v)", code, err)
+	}
+	if want := fmt.Sprintf("curl -X DELETE '%s/api/datasources/%d'", srv.URL, id); !strings.Contains(out, want) {
+		t.Errorf("expected %q in output, got:\n%s", want, out)
+	}
+	for _, req := range srv.Requests() {
+		if !strings.HasPrefix(req, "GET ") {
+			t.Errorf("expected no write requests, got %s", req)
+		}
+	}
+	if len(srv.Datasources(grafanatest.MainOrgID)) != 1 {
+		t.Errorf("expected the datasource to be kept")
+	}
+}
+
+func TestRunCommandLargeOutput(t *testing.T) {
+	srv := useFakeGrafana(t)
+	description := strings.Repeat("x", 256<<10)
+	srv.AddDashboard(grafanatest.MainOrgID, "", map[string]interface{}{"uid": "big", "title": "Big", "description": description})
+
+	// More than a pipe buffer of output must not block the command.
+	out, err := runCommand("dash", "read", "big")
+	if err != nil {
+		t.Fatalf("dash read failed: %v", err)
+	}
+	if !strings.Contains(out, description) {
+		t.Errorf("expected the whole dashboard in the output, got %d bytes", len(out))
+	}
+}
+
+// useFakeGrafana starts a fake Grafana and makes it the active profile of a
+// temporary config.
+func useFakeGrafana(t *testing.T) *grafanatest.Server {
+	t.Helper()
+	srv := grafanatest.NewServer()
+	t.Cleanup(srv.Close)
+	os.Setenv("GCLI_CONFIG_PATH", filepath.Join(t.TempDir(), "config.yaml"))
+	t.Cleanup(func() { os.Unsetenv("GCLI_CONFIG_PATH") })
+	if err := config.SaveProfile(srv.Profile("test")); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.SetActive("test"); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// runCommand executes gcli with args and returns what it printed to stdout.
+// Flags are reset afterwards so they do not leak into later runs.
+func runCommand(args ...string) (string, error) {
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	// Read while the command runs; output beyond the pipe buffer would
+	// block it otherwise.
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(done)
+	}()
+
+	rootCmd.SetArgs(args)
+	c, err := executeRoot()
+	w.Close()
+	<-done
+	r.Close()
+	os.Stdout = old
+	resetFlags(rootCmd.PersistentFlags())
+	resetFlags(c.Flags())
+	return buf.String(), err
+}
+
+func resetFlags(flags *pflag.FlagSet) {
+	flags.VisitAll(func(f *pflag.Flag) {
+		f.Value.Set(f.DefValue)
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		}
+		f.Changed = false
+	})
+}
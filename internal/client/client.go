@@ -0,0 +1,244 @@
+// Package client provides the Grafana HTTP API client shared by all gcli
+// commands. It owns authentication, the organization header, URL joining,
+// JSON encoding/decoding and error handling.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gcli/internal/config"
+)
+
+// Client talks to a single Grafana instance on behalf of a profile.
+type Client struct {
+	BaseURL string
+	User    string
+	Pass    string
+	// Token takes precedence over User and Pass when set.
+	Token string
+	// OrgID is sent as X-Grafana-Org-Id when not empty.
+	OrgID string
+	// Headers are added to every request.
+	Headers    map[string]string
+	HTTPClient *http.Client
+}
+
+// New returns a client for the given profile and organization ID.
+func New(p *config.Profile, orgID string) (*Client, error) {
+	httpClient, err := newHTTPClient(p)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", p.Name, err)
+	}
+	return &Client{
+		BaseURL:    strings.TrimRight(p.URL, "/"),
+		User:       p.User,
+		Pass:       p.Pass,
+		Token:      p.Token,
+		OrgID:      orgID,
+		Headers:    p.Headers,
+		HTTPClient: httpClient,
+	}, nil
+}
+
+// FromActive returns a client for the active profile and organization.
+func FromActive() (*Client, error) {
+	profile, err := config.GetActive()
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
+	}
+	activeOrg, _ := config.GetActiveOrg()
+	return New(profile, activeOrg)
+}
+
+// FromProfile returns a client for a stored profile and the organization
+// selected for it.
+func FromProfile(name string) (*Client, error) {
+	profile, err := config.GetProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ResolveCredentials(profile); err != nil {
+		return nil, err
+	}
+	return New(profile, profile.OrgID)
+}
+
+// URL joins path onto the base URL of the Grafana instance.
+func (c *Client) URL(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return c.BaseURL + path
+}
+
+// NewRequest builds an authenticated request for path.
+func (c *Client) NewRequest(method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.URL(path), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else if c.User != "" || c.Pass != "" {
+		req.SetBasicAuth(c.User, c.Pass)
+	}
+	if c.OrgID != "" {
+		req.Header.Set("X-Grafana-Org-Id", c.OrgID)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// Do sends req and returns the raw response without checking its status.
+// It honours the options set with SetTrace and SetDryRun.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if dryRun && isWrite(req.Method) {
+		return nil, printDryRun(req)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return sendTraced(httpClient, req)
+}
+
+// Response is a fully read Grafana response.
+type Response struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+}
+
+// Send sends a request and returns the response whatever its status.
+//
+// in may be nil, a []byte or io.Reader sent as-is, or any other value which is
+// encoded as JSON.
+func (c *Client) Send(method, path string, in interface{}) (*Response, error) {
+	body, err := encodeBody(in)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.NewRequest(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.SendRequest(req)
+}
+
+// SendRequest sends a request built with NewRequest, possibly adjusted by the
+// caller, and reads the whole response whatever its status.
+func (c *Client) SendRequest(req *http.Request) (*Response, error) {
+	resp, err := c.Do(req)
+	if errors.Is(err, ErrCurlOnly) || errors.Is(err, ErrDryRun) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, &NetworkError{Method: req.Method, URL: req.URL.Redacted(), Err: unwrapURLError(err)}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &NetworkError{Method: req.Method, URL: req.URL.Redacted(), Err: err}
+	}
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       data,
+	}, nil
+}
+
+// Check returns an *APIError for a non-2xx response.
+func (r *Response) Check(method, path string) error {
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return newAPIError(method, path, r)
+	}
+	return nil
+}
+
+// Call sends a request and decodes the response into out.
+//
+// out may be nil to discard the body, a *[]byte to receive the raw body, a
+// *Response to receive the whole response, or any value json.Unmarshal
+// accepts. A non-2xx status is returned as an *APIError.
+func (c *Client) Call(method, path string, in, out interface{}) error {
+	resp, err := c.Send(method, path, in)
+	if err != nil {
+		return err
+	}
+	if err := resp.Check(method, path); err != nil {
+		return err
+	}
+	return decodeBody(resp, out)
+}
+
+// Get fetches path and decodes the response into out.
+func (c *Client) Get(path string, out interface{}) error {
+	return c.Call(http.MethodGet, path, nil, out)
+}
+
+// Post sends in to path and decodes the response into out.
+func (c *Client) Post(path string, in, out interface{}) error {
+	return c.Call(http.MethodPost, path, in, out)
+}
+
+// Put sends in to path and decodes the response into out.
+func (c *Client) Put(path string, in, out interface{}) error {
+	return c.Call(http.MethodPut, path, in, out)
+}
+
+// Delete deletes path and decodes the response into out.
+func (c *Client) Delete(path string, out interface{}) error {
+	return c.Call(http.MethodDelete, path, nil, out)
+}
+
+func encodeBody(in interface{}) (io.Reader, error) {
+	switch v := in.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return bytes.NewReader(v), nil
+	case io.Reader:
+		return v, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		return bytes.NewReader(b), nil
+	}
+}
+
+func decodeBody(resp *Response, out interface{}) error {
+	switch v := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*v = resp.Body
+		return nil
+	case *Response:
+		*v = *resp
+		return nil
+	default:
+		if err := json.Unmarshal(resp.Body, v); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+		return nil
+	}
+}
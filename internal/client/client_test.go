@@ -0,0 +1,612 @@
+package client
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gcli/internal/config"
+)
+
+func TestClientCall(t *testing.T) {
+	// Mock Grafana API
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/org":
+			fmt.Fprintf(w, `{"id":%s}`, r.Header.Get("X-Grafana-Org-Id"))
+		case "/api/echo":
+			if r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			io.Copy(w, r.Body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"message":"Not found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := New(&config.Profile{URL: ts.URL + "/", User: "admin", Pass: "secret"}, "3")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	var org struct {
+		ID int `json:"id"`
+	}
+	if err := c.Get("api/org", &org); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if org.ID != 3 {
+		t.Errorf("expected org header 3, got %d", org.ID)
+	}
+
+	var echo map[string]string
+	if err := c.Post("/api/echo", map[string]string{"name": "x"}, &echo); err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	if echo["name"] != "x" {
+		t.Errorf("expected echoed payload, got %v", echo)
+	}
+
+	err = c.Delete("/api/missing", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", apiErr.StatusCode)
+	}
+}
+
+func TestClientBearerToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer glsa_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintln(w, `{}`)
+	}))
+	defer ts.Close()
+
+	c, err := New(&config.Profile{URL: ts.URL, Token: "glsa_test"}, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := c.Get("/api/user", nil); err != nil {
+		t.Errorf("expected bearer auth to succeed, got %v", err)
+	}
+}
+
+func TestClientTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{}`)
+	}))
+	defer ts.Close()
+
+	tmpDir, _ := os.MkdirTemp("", "gcli-client-test-*")
+	defer os.RemoveAll(tmpDir)
+	caFile := filepath.Join(tmpDir, "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	os.WriteFile(caFile, pemBytes, 0o600)
+
+	tests := []struct {
+		name    string
+		profile config.Profile
+		wantErr bool
+	}{
+		{"untrusted", config.Profile{URL: ts.URL}, true},
+		{"ca_file", config.Profile{URL: ts.URL, CAFile: caFile}, false},
+		{"insecure", config.Profile{URL: ts.URL, InsecureSkipVerify: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(&tt.profile, "")
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			err = c.Get("/api/health", nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientProxyTimeoutAndHeaders(t *testing.T) {
+	// Plain HTTP proxies receive the absolute target URL.
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		if r.Header.Get("X-WEBAUTH-USER") != "admin" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/api/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprintln(w, `{}`)
+	}))
+	defer proxy.Close()
+
+	c, err := New(&config.Profile{
+		URL:     "http://grafana.invalid",
+		Proxy:   proxy.URL,
+		Timeout: "50ms",
+		Headers: map[string]string{"X-WEBAUTH-USER": "admin"},
+	}, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if err := c.Get("/api/health", nil); err != nil {
+		t.Fatalf("Get through proxy failed: %v", err)
+	}
+	if proxied != "http://grafana.invalid/api/health" {
+		t.Errorf("expected request via proxy, got %q", proxied)
+	}
+
+	if err := c.Get("/api/slow", nil); err == nil {
+		t.Errorf("expected timeout error")
+	}
+
+	if _, err := New(&config.Profile{URL: "http://x", Timeout: "soon"}, ""); err == nil {
+		t.Errorf("expected error for invalid timeout")
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/dashboards/uid/missing":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Dashboard not found","status":"not-found"}`)
+		case "/api/user":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"invalid API key"}`)
+		case "/api/admin/users":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"Permission denied"}`)
+		case "/api/dashboards/db":
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `{"message":"The dashboard has been changed by someone else","status":"version-mismatch"}`)
+		case "/api/folders":
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"message":"a folder with the same name already exists"}`)
+		case "/api/orgs":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `[{"fieldNames":["Name"],"classification":"RequiredError","message":"Required"}]`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, "boom")
+		}
+	}))
+	defer ts.Close()
+
+	c, err := New(&config.Profile{URL: ts.URL}, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tests := []struct {
+		method, path string
+		kind         error
+		msg          string
+	}{
+		{http.MethodGet, "/api/dashboards/uid/missing", ErrNotFound, "404 Not Found: Dashboard not found"},
+		{http.MethodGet, "/api/user", ErrUnauthorized, "401 Unauthorized: invalid API key"},
+		{http.MethodGet, "/api/admin/users", ErrForbidden, "403 Forbidden: Permission denied"},
+		{http.MethodPost, "/api/dashboards/db", ErrConflict, "412 Precondition Failed: The dashboard has been changed by someone else"},
+		{http.MethodPost, "/api/folders", ErrConflict, "409 Conflict: a folder with the same name already exists"},
+		{http.MethodPost, "/api/orgs", ErrValidation, "422 Unprocessable Entity: Name: Required"},
+		{http.MethodGet, "/api/other", nil, "500 Internal Server Error boom"},
+	}
+	for _, tt := range tests {
+		err := c.Call(tt.method, tt.path, nil, nil)
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.path)
+		}
+		if tt.kind != nil && !errors.Is(err, tt.kind) {
+			t.Errorf("%s: expected %v, got %v", tt.path, tt.kind, err)
+		}
+		if err.Error() != tt.msg {
+			t.Errorf("%s: expected message %q, got %q", tt.path, tt.msg, err.Error())
+		}
+	}
+
+	// Nothing listens on the closed server's address.
+	addr := ts.URL
+	ts.Close()
+	noRetries := 0
+	c, _ = New(&config.Profile{URL: addr, Retries: &noRetries}, "")
+	err = c.Get("/api/health", nil)
+	var netErr *NetworkError
+	if !errors.Is(err, ErrNetwork) || !errors.As(err, &netErr) {
+		t.Fatalf("expected network error, got %v", err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Errorf("network error must not match ErrNotFound")
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	defer func(base, max time.Duration) { retryBaseDelay, retryMaxDelay = base, max }(retryBaseDelay, retryMaxDelay)
+	retryBaseDelay, retryMaxDelay = time.Millisecond, 5*time.Millisecond
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts[r.Method+" "+r.URL.Path]++
+		n := attempts[r.Method+" "+r.URL.Path]
+		if r.Method == http.MethodPut {
+			b, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(b))
+		}
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/api/flaky":
+			// Fails twice with different transient statuses, then succeeds.
+			if n == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			if n == 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `{"ok":true}`)
+		case "/api/throttled":
+			if n == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, `{}`)
+		case "/api/reset":
+			if n == 1 {
+				// Close the connection without answering.
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			fmt.Fprint(w, `{}`)
+		case "/api/later", "/api/soon":
+			if r.URL.Path == "/api/soon" {
+				w.Header().Set("Retry-After", "2")
+			} else {
+				w.Header().Set("Retry-After", "3600")
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	count := func(key string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts[key]
+	}
+
+	c, err := New(&config.Profile{URL: ts.URL}, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if err := c.Get("/api/flaky", nil); err != nil {
+		t.Errorf("expected flaky GET to succeed after retries, got %v", err)
+	}
+	if n := count("GET /api/flaky"); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+
+	if err := c.Put("/api/flaky", map[string]string{"title": "x"}, nil); err != nil {
+		t.Errorf("expected flaky PUT to succeed after retries, got %v", err)
+	}
+	for _, b := range bodies {
+		if b != `{"title":"x"}` {
+			t.Errorf("expected the body to be resent on retry, got %q", b)
+		}
+	}
+
+	start := time.Now()
+	if err := c.Get("/api/throttled", nil); err != nil {
+		t.Errorf("expected throttled GET to succeed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected Retry-After to be honoured, retried after %v", elapsed)
+	}
+
+	if err := c.Get("/api/reset", nil); err != nil {
+		t.Errorf("expected retry after connection reset, got %v", err)
+	}
+
+	// POST is not idempotent and must not be retried.
+	if err := c.Post("/api/flaky", nil, nil); err == nil {
+		t.Errorf("expected POST to fail without retry")
+	}
+	if n := count("POST /api/flaky"); n != 1 {
+		t.Errorf("expected 1 POST attempt, got %d", n)
+	}
+
+	// Give up after the configured retries.
+	if err := c.Get("/api/down", nil); err == nil {
+		t.Errorf("expected error from a server that stays down")
+	}
+	if n := count("GET /api/down"); n != DefaultRetries+1 {
+		t.Errorf("expected %d attempts, got %d", DefaultRetries+1, n)
+	}
+
+	// A Retry-After beyond the limit returns the response immediately.
+	start = time.Now()
+	var apiErr *APIError
+	if err := c.Get("/api/later", nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %v", err)
+	}
+	if time.Since(start) > time.Second || count("GET /api/later") != 1 {
+		t.Errorf("expected no retry for a long Retry-After")
+	}
+
+	// A Retry-After past the client timeout returns the response instead of
+	// a timeout error.
+	short, _ := New(&config.Profile{URL: ts.URL, Timeout: "1s"}, "")
+	start = time.Now()
+	if err := short.Get("/api/soon", nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected 429 within the timeout, got %v", err)
+	}
+	if time.Since(start) > time.Second || count("GET /api/soon") != 1 {
+		t.Errorf("expected no retry for a Retry-After past the timeout")
+	}
+
+	one := 1
+	c, _ = New(&config.Profile{URL: ts.URL, Retries: &one}, "")
+	c.Get("/api/down2", nil)
+	if n := count("GET /api/down2"); n != 2 {
+		t.Errorf("expected 2 attempts with retries: 1, got %d", n)
+	}
+}
+
+func TestRetryKeepsRequest(t *testing.T) {
+	defer func(base time.Duration) { retryBaseDelay = base }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	var sent []*http.Request
+	var bodies []string
+	rt := &retryTransport{retries: 2, next: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sent = append(sent, r)
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		status := http.StatusServiceUnavailable
+		if len(sent) == 3 {
+			status = http.StatusOK
+		}
+		return &http.Response{StatusCode: status, Status: http.StatusText(status), Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+	})}
+
+	req, _ := http.NewRequest(http.MethodPut, "http://grafana.test/api/x", strings.NewReader(`{"a":1}`))
+	body := req.Body
+	resp, err := rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected success on the third attempt, got %v (%v)", resp, err)
+	}
+	if req.Body != body {
+		t.Errorf("the caller's request body was replaced")
+	}
+	for i, r := range sent {
+		if i > 0 && r == req {
+			t.Errorf("attempt %d reused the caller's request", i+1)
+		}
+		if bodies[i] != `{"a":1}` {
+			t.Errorf("attempt %d sent body %q", i+1, bodies[i])
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestClientRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+	defer ts.Close()
+
+	c, err := New(&config.Profile{URL: ts.URL, RateLimit: 20}, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := c.Get("/api/health", nil); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	}
+	// 5 requests at 20/s need at least 4 intervals of 50ms.
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected requests to be rate limited, took %v", elapsed)
+	}
+}
+
+func TestClientTrace(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		io.Copy(w, r.Body)
+	}))
+	defer ts.Close()
+	defer SetTrace(TraceOptions{})
+
+	c, err := New(&config.Profile{URL: ts.URL, Token: "glsa_secret", Headers: map[string]string{"CF-Access-Client-Secret": "cfsecret"}}, "2")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	payload := map[string]interface{}{"name": "prom", "secureJsonData": map[string]string{"password": "dbpass"}}
+
+	tests := []struct {
+		name   string
+		opts   TraceOptions
+		want   []string
+		reject []string
+	}{
+		{"requests", TraceOptions{Verbose: TraceRequests}, []string{"POST " + ts.URL + "/api/datasources 200 OK ("}, []string{"X-Grafana-Org-Id"}},
+		{"headers", TraceOptions{Verbose: TraceHeaders}, []string{"> POST ", "> Authorization: Bearer ********", "> Cf-Access-Client-Secret: ********", "> X-Grafana-Org-Id: 2", "< 200 OK", "< Content-Type: application/json"}, []string{`"name"`}},
+		{"bodies", TraceOptions{Verbose: TraceBodies}, []string{`"name":"prom"`, `"secureJsonData":"********"`}, []string{"dbpass"}},
+		{"curl", TraceOptions{Curl: CurlRun}, []string{"curl -X POST '" + ts.URL + "/api/datasources'", `-H "Authorization: Bearer $GRAFANA_TOKEN"`, "-H 'Cf-Access-Client-Secret: ********'", `--data-binary '{"name":"prom","secureJsonData":"********"}'`}, []string{"dbpass"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.opts.Out = &buf
+			SetTrace(tt.opts)
+			var out map[string]interface{}
+			if err := c.Post("/api/datasources", payload, &out); err != nil {
+				t.Fatalf("Post failed: %v", err)
+			}
+			if out["name"] != "prom" {
+				t.Errorf("expected the body to reach the server, got %v", out)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(buf.String(), w) {
+					t.Errorf("expected %q in trace, got:\n%s", w, buf.String())
+				}
+			}
+			for _, r := range append(tt.reject, "glsa_secret", "cfsecret") {
+				if strings.Contains(buf.String(), r) {
+					t.Errorf("did not expect %q in trace, got:\n%s", r, buf.String())
+				}
+			}
+		})
+	}
+
+	// In CurlOnly mode reads are printed and sent, writes only printed.
+	var buf bytes.Buffer
+	SetTrace(TraceOptions{Curl: CurlOnly, Out: &buf})
+	c.User, c.Pass, c.Token = "admin", "pw", ""
+	mu.Lock()
+	sent = nil
+	mu.Unlock()
+	if err := c.Get("/api/search?query=cpu", nil); err != nil {
+		t.Fatalf("expected the GET to be sent, got %v", err)
+	}
+	if err := c.Delete("/api/datasources/uid/prom", nil); !errors.Is(err, ErrCurlOnly) {
+		t.Fatalf("expected ErrCurlOnly for the DELETE, got %v", err)
+	}
+	for _, want := range []string{
+		`curl '` + ts.URL + `/api/search?query=cpu' -u "admin:$GRAFANA_PASSWORD" -H 'Cf-Access-Client-Secret: ********' -H 'X-Grafana-Org-Id: 2'`,
+		`curl -X DELETE '` + ts.URL + `/api/datasources/uid/prom'`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q, got %q", want, buf.String())
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || sent[0] != "GET /api/search" {
+		t.Errorf("expected only the GET to be sent, got %v", sent)
+	}
+}
+
+func TestClientGetAll(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		q := r.URL.Query()
+		page, _ := strconv.Atoi(q.Get("page"))
+		switch r.URL.Path {
+		case "/api/search":
+			// 5 dashboards, limit/page style.
+			size, _ := strconv.Atoi(q.Get("limit"))
+			var items []string
+			for i := (page-1)*size + 1; i <= page*size && i <= 5; i++ {
+				items = append(items, fmt.Sprintf(`{"uid":"d%d"}`, i))
+			}
+			fmt.Fprintf(w, "[%s]", strings.Join(items, ","))
+		case "/api/users/search":
+			// 3 users, perpage/page style wrapped in an object.
+			size, _ := strconv.Atoi(q.Get("perpage"))
+			var items []string
+			for i := (page-1)*size + 1; i <= page*size && i <= 3; i++ {
+				items = append(items, fmt.Sprintf(`{"login":"u%d"}`, i))
+			}
+			fmt.Fprintf(w, `{"totalCount":3,"users":[%s],"page":%d,"perPage":%d}`, strings.Join(items, ","), page, size)
+		case "/api/unpaged":
+			fmt.Fprint(w, `[{"id":1},{"id":2}]`)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := New(&config.Profile{URL: ts.URL}, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	var dashboards []struct {
+		UID string `json:"uid"`
+	}
+	if err := c.GetAll("/api/search?type=dash-db&limit=2", 0, &dashboards); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(dashboards) != 5 || dashboards[4].UID != "d5" {
+		t.Errorf("expected 5 dashboards across pages, got %v", dashboards)
+	}
+	if len(requests) != 3 || !strings.Contains(requests[2], "page=3") || !strings.Contains(requests[2], "type=dash-db") {
+		t.Errorf("expected 3 page requests keeping the query, got %v", requests)
+	}
+
+	requests = nil
+	var raw []byte
+	if err := c.GetAll("/api/search", 3, &raw); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if string(raw) != `[{"uid":"d1"},{"uid":"d2"},{"uid":"d3"}]` || len(requests) != 1 || !strings.Contains(requests[0], "limit=3") {
+		t.Errorf("expected the limit to size a single page, got %s after %v", raw, requests)
+	}
+
+	requests = nil
+	var users []map[string]string
+	if err := c.GetAll("/api/users/search?perpage=2", 0, &users); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(users) != 3 || users[2]["login"] != "u3" || len(requests) != 2 {
+		t.Errorf("expected 3 users in 2 pages, got %v after %v", users, requests)
+	}
+
+	// An endpoint ignoring the paging parameters must not loop forever.
+	var unpaged []map[string]int
+	if err := c.GetAll("/api/unpaged?limit=2", 0, &unpaged); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(unpaged) != 2 {
+		t.Errorf("expected 2 items from an unpaged endpoint, got %v", unpaged)
+	}
+}
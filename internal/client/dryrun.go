@@ -0,0 +1,56 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrDryRun is returned instead of sending a write request in dry-run mode.
+var ErrDryRun = errors.New("dry run: request not sent")
+
+var dryRun bool
+
+// SetDryRun enables or disables dry-run mode for all clients. In dry-run mode
+// reads are sent as usual, while write requests (POST, PUT, PATCH, DELETE)
+// are printed and fail with ErrDryRun.
+func SetDryRun(on bool) {
+	dryRun = on
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// printDryRun prints the method, path and payload of a write request.
+func printDryRun(req *http.Request) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = peekBody(req); err != nil {
+			return err
+		}
+	}
+	w := curlOut()
+	target := req.URL.RequestURI()
+	if org := req.Header.Get("X-Grafana-Org-Id"); org != "" {
+		target += " (org " + org + ")"
+	}
+	fmt.Fprintf(w, "DRY RUN: %s %s\n", req.Method, target)
+	if len(body) == 0 {
+		return ErrDryRun
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, []byte(redactBody(body)), "", "  "); err != nil {
+		fmt.Fprintln(w, string(body))
+	} else {
+		fmt.Fprintln(w, pretty.String())
+	}
+	return ErrDryRun
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Error kinds. API and network errors match them with errors.Is, e.g.
+// errors.Is(err, client.ErrNotFound).
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrNetwork      = errors.New("network error")
+)
+
+// APIError is returned when Grafana answers with a non-2xx status.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Status     string
+	// Message is the error message from Grafana's JSON error body, if any.
+	Message string
+	Body    []byte
+}
+
+func newAPIError(method, path string, resp *Response) *APIError {
+	return &APIError{
+		Method:     method,
+		Path:       path,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Message:    parseErrorMessage(resp.Body),
+		Body:       resp.Body,
+	}
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s: %s", e.Status, e.Message)
+	}
+	body := strings.TrimSpace(string(e.Body))
+	if body == "" {
+		return e.Status
+	}
+	return fmt.Sprintf("%s %s", e.Status, body)
+}
+
+// Unwrap returns the error kind for the status code, or nil.
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		// Grafana answers 412 when a dashboard version or name clashes.
+		return ErrConflict
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrValidation
+	}
+	return nil
+}
+
+// parseErrorMessage extracts the message from Grafana's error bodies:
+// {"message": "..."}, {"error": "..."} or a list of binding errors such as
+// [{"fieldNames": ["Name"], "message": "Required"}].
+func parseErrorMessage(body []byte) string {
+	var obj struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &obj); err == nil {
+		if obj.Message != "" {
+			return obj.Message
+		}
+		return obj.Error
+	}
+
+	var list []struct {
+		FieldNames []string `json:"fieldNames"`
+		Message    string   `json:"message"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return ""
+	}
+	var msgs []string
+	for _, e := range list {
+		if e.Message == "" {
+			continue
+		}
+		if len(e.FieldNames) > 0 {
+			msgs = append(msgs, strings.Join(e.FieldNames, ", ")+": "+e.Message)
+		} else {
+			msgs = append(msgs, e.Message)
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// NetworkError is returned when no response was received, e.g. because the
+// host is unreachable or the request timed out.
+type NetworkError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrNetwork.
+func (e *NetworkError) Is(target error) bool { return target == ErrNetwork }
+
+// unwrapURLError drops the *url.Error wrapper, whose message repeats the
+// method and URL.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultPageSize is the page size used by GetAll unless the path sets one.
+const DefaultPageSize = 1000
+
+// maxPages stops GetAll on endpoints that never return a short page.
+const maxPages = 10000
+
+// pageParam returns the query parameter holding the page size of path.
+// Grafana's search endpoints for users, teams and service accounts, as well as
+// /api/orgs and /api/users, take perpage and page; /api/search, /api/folders
+// and most others take limit and page.
+func pageParam(path string) string {
+	switch {
+	case path == "/api/search":
+		return "limit"
+	case strings.HasSuffix(path, "/search"), path == "/api/orgs", path == "/api/users":
+		return "perpage"
+	}
+	return "limit"
+}
+
+// GetAll fetches every page of a paginated list endpoint and decodes the
+// items of all pages, as one JSON array, into out. At most limit items are
+// returned when limit is positive. out accepts the same values as in Call.
+//
+// Pages may be JSON arrays or objects holding the items in their only array
+// field, like {"totalCount": 2, "users": [...]}.
+func (c *Client) GetAll(path string, limit int, out interface{}) error {
+	u, err := url.Parse(path)
+	if err != nil {
+		return fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	q := u.Query()
+	param := pageParam(u.Path)
+	if q.Get("perpage") != "" || q.Get("perPage") != "" {
+		param = "perpage"
+	}
+	pageSize, _ := strconv.Atoi(q.Get(param))
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+		if limit > 0 && limit < pageSize {
+			pageSize = limit
+		}
+	}
+	q.Set(param, strconv.Itoa(pageSize))
+
+	var items []json.RawMessage
+	var prevFirst json.RawMessage
+	for page := 1; page <= maxPages; page++ {
+		q.Set("page", strconv.Itoa(page))
+		u.RawQuery = q.Encode()
+
+		var body []byte
+		if err := c.Get(u.String(), &body); err != nil {
+			return err
+		}
+		pageItems, total, err := pageItems(body)
+		if err != nil {
+			return err
+		}
+		// An endpoint ignoring the page parameter returns the first page again.
+		if len(pageItems) > 0 && prevFirst != nil && bytes.Equal(pageItems[0], prevFirst) {
+			break
+		}
+		items = append(items, pageItems...)
+		if len(pageItems) > 0 {
+			prevFirst = pageItems[0]
+		}
+
+		if len(pageItems) < pageSize || (limit > 0 && len(items) >= limit) || (total >= 0 && len(items) >= total) {
+			break
+		}
+	}
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return decodeBody(&Response{StatusCode: 200, Status: "200 OK", Body: data}, out)
+}
+
+// pageItems returns the items of a page and its totalCount, or -1 when the
+// page does not report one.
+func pageItems(body []byte) ([]json.RawMessage, int, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(body, &list); err == nil {
+		return list, -1, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse response: %w", err)
+	}
+	total := -1
+	if raw, ok := obj["totalCount"]; ok {
+		json.Unmarshal(raw, &total)
+	}
+	for _, raw := range obj {
+		if len(raw) > 0 && raw[0] == '[' {
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, 0, fmt.Errorf("failed to parse response: %w", err)
+			}
+			return list, total, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("failed to parse response: no list of items found")
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRetries is the number of retries for profiles without a retries
+// setting.
+const DefaultRetries = 3
+
+// Backoff parameters. They are variables so tests can shorten them.
+var (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+	// maxRetryAfter is the longest Retry-After gcli waits for; longer
+	// waits, and waits past the request's deadline, return the response
+	// instead.
+	maxRetryAfter = time.Minute
+)
+
+// retryTransport retries idempotent requests that failed with a network
+// error or a transient status, with exponential backoff and jitter. A
+// Retry-After header from the server takes precedence over the backoff.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+	limiter *rateLimiter
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := isIdempotent(req.Method) && (req.Body == nil || req.GetBody != nil)
+	for attempt := 0; ; attempt++ {
+		// RoundTrippers must not modify the request, so retries send a
+		// copy with a fresh body.
+		r := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		if err := t.limiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.next.RoundTrip(r)
+		if !retryable || attempt >= t.retries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				if after > maxRetryAfter {
+					return resp, nil
+				}
+				delay = after
+			}
+		}
+		// The client timeout covers retries too. Waiting past it would only
+		// turn the response into a timeout error.
+		if deadline, ok := req.Context().Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return resp, err
+		}
+		if resp != nil {
+			// Drain the body so the connection can be reused.
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		reason := "network error"
+		if resp != nil {
+			reason = resp.Status
+		}
+		tracef(TraceRequests, "%s %s: %s, retrying in %s (%d/%d)", req.Method, req.URL.Redacted(), reason, delay.Round(time.Millisecond), attempt+1, t.retries)
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if req.Context().Err() != nil {
+			// Cancelled or timed out as a whole.
+			return false
+		}
+		var certErr *tls.CertificateVerificationError
+		return !errors.As(err, &certErr)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay before retry attempt+1: exponential growth capped
+// at retryMaxDelay, randomized to between half and the full value.
+func backoff(attempt int) time.Duration {
+	d := retryBaseDelay << uint(attempt)
+	if d > retryMaxDelay || d <= 0 {
+		d = retryMaxDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimiter spaces requests evenly at a maximum rate. A nil limiter does
+// not limit.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+	return sleep(ctx, time.Until(slot))
+}
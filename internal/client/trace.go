@@ -0,0 +1,266 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gcli/internal/redact"
+)
+
+// Verbosity levels for TraceOptions.Verbose.
+const (
+	// TraceRequests logs method, URL, status and latency of each request.
+	TraceRequests = 1
+	// TraceHeaders also logs request and response headers.
+	TraceHeaders = 2
+	// TraceBodies also logs request and response bodies.
+	TraceBodies = 3
+)
+
+// Curl modes for TraceOptions.Curl.
+const (
+	// CurlOnly prints the curl command of every request but sends only
+	// reads (GET, HEAD, OPTIONS).
+	CurlOnly = "only"
+	// CurlRun prints the curl command and sends the request.
+	CurlRun = "run"
+)
+
+// ErrCurlOnly is returned instead of sending a write request in CurlOnly
+// mode.
+var ErrCurlOnly = errors.New("request printed as curl command and not sent")
+
+// TraceOptions configures request tracing for all clients.
+type TraceOptions struct {
+	Verbose int
+	Curl    string
+	// Out receives traces, curl commands and dry-run output; defaults to
+	// stderr for traces and stdout for the others.
+	Out io.Writer
+}
+
+var trace TraceOptions
+
+// SetTrace sets the tracing options, typically from the global -v and
+// --as-curl flags.
+func SetTrace(o TraceOptions) {
+	trace = o
+}
+
+func traceOut() io.Writer {
+	if trace.Out != nil {
+		return trace.Out
+	}
+	return os.Stderr
+}
+
+func curlOut() io.Writer {
+	if trace.Out != nil {
+		return trace.Out
+	}
+	return os.Stdout
+}
+
+// tracef logs a line when the verbosity is at least level.
+func tracef(level int, format string, args ...interface{}) {
+	if trace.Verbose >= level {
+		fmt.Fprintf(traceOut(), format+"\n", args...)
+	}
+}
+
+// sendTraced sends req through httpClient, printing the curl command and
+// trace output requested by the trace options.
+func sendTraced(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && (trace.Curl != "" || trace.Verbose >= TraceBodies) {
+		var err error
+		if reqBody, err = peekBody(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if trace.Curl != "" {
+		fmt.Fprintln(curlOut(), curlCommand(req, reqBody))
+		// Reads are still sent, so commands that look something up
+		// before changing it get as far as printing the change.
+		if trace.Curl == CurlOnly && isWrite(req.Method) {
+			return nil, ErrCurlOnly
+		}
+	}
+	if trace.Verbose < TraceRequests {
+		return httpClient.Do(req)
+	}
+
+	w := traceOut()
+	if trace.Verbose >= TraceHeaders {
+		fmt.Fprintf(w, "> %s %s\n", req.Method, req.URL.Redacted())
+		writeHeaders(w, "> ", req.Header)
+		if reqBody != nil && trace.Verbose >= TraceBodies {
+			fmt.Fprintf(w, ">\n%s\n", redactBody(reqBody))
+		}
+	}
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(w, "%s %s failed after %s: %v\n", req.Method, req.URL.Redacted(), elapsed, err)
+		return nil, err
+	}
+	if trace.Verbose < TraceHeaders {
+		fmt.Fprintf(w, "%s %s %s (%s)\n", req.Method, req.URL.Redacted(), resp.Status, elapsed)
+		return resp, nil
+	}
+
+	fmt.Fprintf(w, "< %s (%s)\n", resp.Status, elapsed)
+	writeHeaders(w, "< ", resp.Header)
+	if trace.Verbose >= TraceBodies {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		fmt.Fprintf(w, "<\n%s\n", redactBody(body))
+	}
+	return resp, nil
+}
+
+// peekBody reads the request body and replaces it, so it can still be sent
+// and retried.
+func peekBody(req *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+func writeHeaders(w io.Writer, prefix string, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range h[name] {
+			if redact.IsSensitiveHeader(name) {
+				v = redact.HeaderValue(name, v)
+			}
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, name, v)
+		}
+	}
+}
+
+const redacted = redact.Mask
+
+// redactBody masks secret fields of a JSON body, such as datasource
+// passwords and secureJsonData. Other bodies are returned as they are.
+func redactBody(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	if !redactJSON(v) {
+		return string(body)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
+
+func redactJSON(v interface{}) bool {
+	changed := false
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, e := range val {
+			if isSecretKey(k) && e != nil && e != "" {
+				val[k] = redacted
+				changed = true
+			} else if redactJSON(e) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, e := range val {
+			if redactJSON(e) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func isSecretKey(k string) bool {
+	k = strings.ToLower(k)
+	if k == "securejsondata" || k == "key" {
+		return true
+	}
+	for _, s := range []string{"password", "secret", "token"} {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// curlCommand returns a shell command equivalent to req. Credentials are
+// replaced by $GRAFANA_TOKEN and $GRAFANA_PASSWORD so the command can be
+// shared and run after exporting them. Secret fields of the body are masked
+// like in traces and have to be filled in before running it.
+func curlCommand(req *http.Request, body []byte) string {
+	parts := []string{"curl"}
+	if req.Method != http.MethodGet || body != nil {
+		parts = append(parts, "-X", req.Method)
+	}
+	parts = append(parts, shellQuote(req.URL.String()))
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range req.Header[name] {
+			switch {
+			case name == "Authorization" && strings.HasPrefix(v, "Bearer "):
+				parts = append(parts, "-H", `"Authorization: Bearer $GRAFANA_TOKEN"`)
+			case name == "Authorization" && strings.HasPrefix(v, "Basic "):
+				user, _, _ := req.BasicAuth()
+				parts = append(parts, "-u", `"`+shellEscapeDouble(user)+`:$GRAFANA_PASSWORD"`)
+			case redact.IsSensitiveHeader(name):
+				parts = append(parts, "-H", shellQuote(name+": "+redact.HeaderValue(name, v)))
+			default:
+				parts = append(parts, "-H", shellQuote(name+": "+v))
+			}
+		}
+	}
+	if body != nil {
+		parts = append(parts, "--data-binary", shellQuote(redactBody(body)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func shellEscapeDouble(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(s)
+}
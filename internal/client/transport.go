@@ -0,0 +1,108 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"gcli/internal/config"
+)
+
+// DefaultTimeout bounds requests of profiles without a timeout setting.
+const DefaultTimeout = 30 * time.Second
+
+var wrapTransport func(http.RoundTripper) http.RoundTripper
+
+// SetTransport installs a function that wraps the transport of every client
+// created afterwards, outside of retries and rate limiting. Tests use it to
+// record or replay requests; nil removes it.
+func SetTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	wrapTransport = wrap
+}
+
+// newHTTPClient builds the HTTP client for a profile, applying its TLS, proxy,
+// timeout, retry and rate limit settings.
+func newHTTPClient(p *config.Profile) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(p)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	if p.Proxy != "" {
+		proxyURL, err := url.Parse(p.Proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", p.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	timeout := DefaultTimeout
+	if p.Timeout != "" {
+		timeout, err = time.ParseDuration(p.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %w", p.Timeout, err)
+		}
+	}
+
+	retries := DefaultRetries
+	if p.Retries != nil {
+		retries = *p.Retries
+	}
+	if retries < 0 {
+		return nil, fmt.Errorf("invalid retries %d", retries)
+	}
+	if p.RateLimit < 0 {
+		return nil, fmt.Errorf("invalid rate_limit %v", p.RateLimit)
+	}
+
+	var rt http.RoundTripper = &retryTransport{
+		next:    transport,
+		retries: retries,
+		limiter: newRateLimiter(p.RateLimit),
+	}
+	if wrapTransport != nil {
+		rt = wrapTransport(rt)
+	}
+	// The timeout covers the whole call, including retries.
+	return &http.Client{Transport: rt, Timeout: timeout}, nil
+}
+
+func newTLSConfig(p *config.Profile) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: p.InsecureSkipVerify,
+	}
+
+	if p.CAFile != "" {
+		pem, err := os.ReadFile(p.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", p.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if p.ClientCert != "" || p.ClientKey != "" {
+		if p.ClientCert == "" || p.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(p.ClientCert, p.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
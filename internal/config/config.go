@@ -1,25 +1,62 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 
+	"gcli/internal/keyring"
+	"gcli/internal/redact"
+
 	"gopkg.in/yaml.v3"
 )
 
 type Profile struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
-	User string `yaml:"user"`
-	Pass string `yaml:"pass"`
+	Name string `yaml:"name" json:"name"`
+	URL  string `yaml:"url" json:"url"`
+	User string `yaml:"user,omitempty" json:"user,omitempty"`
+	Pass string `yaml:"pass,omitempty" json:"pass,omitempty"`
+	// Token is a service account token or API key. When set it is sent as a
+	// Bearer token instead of basic auth.
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+	// CredentialCommand is a shell command printing the secret on stdout.
+	CredentialCommand string `yaml:"credential_command,omitempty" json:"credential_command,omitempty"`
+	// Keyring marks the secret as stored in the OS secret service.
+	Keyring bool `yaml:"keyring,omitempty" json:"keyring,omitempty"`
+	// OrgID is the organization selected with `gcli org use` for this
+	// profile.
+	OrgID string `yaml:"org_id,omitempty" json:"org_id,omitempty"`
+
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`
+	// ClientCert and ClientKey are PEM files used for mutual TLS.
+	ClientCert string `yaml:"client_cert,omitempty" json:"client_cert,omitempty"`
+	ClientKey  string `yaml:"client_key,omitempty" json:"client_key,omitempty"`
+	// InsecureSkipVerify disables server certificate verification.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"`
+
+	// Proxy is an http, https or socks5 proxy URL. When empty the
+	// HTTP_PROXY/HTTPS_PROXY environment variables apply.
+	Proxy string `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// Timeout bounds each request, e.g. "30s". Defaults to 30s.
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Headers are sent with every request, e.g. for an auth proxy.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// Retries is how often idempotent requests are retried after transient
+	// failures. Defaults to 3; 0 disables retries.
+	Retries *int `yaml:"retries,omitempty" json:"retries,omitempty"`
+	// RateLimit caps the number of requests per second. 0 means no limit.
+	RateLimit float64 `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
 }
 
 type fileConfig struct {
-	Active    string             `yaml:"active"`
-	ActiveOrg string             `yaml:"active_org"`
-	Profiles  map[string]Profile `yaml:"profiles"`
+	// Version is the schema version, see CurrentVersion.
+	Version  int                `yaml:"version"`
+	Active   string             `yaml:"active"`
+	Profiles map[string]Profile `yaml:"profiles"`
 }
 
 func configFilePath() (string, error) {
@@ -37,50 +74,201 @@ func configFilePath() (string, error) {
 	return filepath.Join(dir, "config.yaml"), nil
 }
 
+// load reads the config file under the config lock.
 func load() (*fileConfig, error) {
 	path, err := configFilePath()
 	if err != nil {
 		return nil, err
 	}
+	unlock, err := lockConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return loadLocked(path)
+}
+
+// update runs a load-modify-save cycle while holding the config lock, so
+// concurrent gcli processes cannot lose each other's changes.
+func update(fn func(cfg *fileConfig) error) error {
+	path, err := configFilePath()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockConfig(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := loadLocked(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(cfg); err != nil {
+		return err
+	}
+	return save(path, cfg)
+}
+
+// loadLocked reads and, if needed, migrates the config file. The caller must
+// hold the config lock.
+func loadLocked(path string) (*fileConfig, error) {
 	data, err := ioutil.ReadFile(path)
 	if os.IsNotExist(err) {
-		return &fileConfig{Profiles: map[string]Profile{}}, nil
+		return &fileConfig{Version: CurrentVersion, Profiles: map[string]Profile{}}, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	var cfg fileConfig
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+	cfg, from, steps, err := upgrade(data)
+	if err != nil {
+		return nil, err
 	}
-	if cfg.Profiles == nil {
-		cfg.Profiles = map[string]Profile{}
+	if len(steps) > 0 {
+		if _, err := writeMigrated(path, data, from, cfg); err != nil {
+			return nil, err
+		}
 	}
-	return &cfg, nil
+	return cfg, nil
 }
 
-func save(cfg *fileConfig) error {
-	path, err := configFilePath()
+// save writes cfg to path atomically: readers see either the old or the new
+// file, never a partial one. The caller must hold the config lock.
+func save(path string, cfg *fileConfig) error {
+	cfg.Version = CurrentVersion
+	out, err := yaml.Marshal(cfg)
 	if err != nil {
 		return err
 	}
-	out, err := yaml.Marshal(cfg)
+	return writeFileAtomic(path, out, 0o600)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(path, out, 0o600)
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
 
 // SaveProfile adds or updates a profile.
 func SaveProfile(p Profile) error {
+	return update(func(cfg *fileConfig) error {
+		cfg.Profiles[p.Name] = p
+		return nil
+	})
+}
+
+// GetProfile returns the stored profile with the given name, without
+// resolving secrets kept outside the config file.
+func GetProfile(name string) (*Profile, error) {
 	cfg, err := load()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %s does not exist", name)
+	}
+	return &p, nil
+}
+
+// DeleteProfile removes a profile and its keyring secret. Deleting the active
+// profile leaves no profile selected.
+func DeleteProfile(name string) error {
+	return update(func(cfg *fileConfig) error {
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			return fmt.Errorf("profile %s does not exist", name)
+		}
+		if p.Keyring {
+			if err := keyring.Default.Delete(name); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+				return fmt.Errorf("failed to remove secret from keyring: %w", err)
+			}
+		}
+		delete(cfg.Profiles, name)
+		if cfg.Active == name {
+			cfg.Active = ""
+		}
+		return nil
+	})
+}
+
+// RenameProfile renames a profile, moving its keyring secret along.
+func RenameProfile(oldName, newName string) error {
+	return update(func(cfg *fileConfig) error {
+		p, ok := cfg.Profiles[oldName]
+		if !ok {
+			return fmt.Errorf("profile %s does not exist", oldName)
+		}
+		if _, exists := cfg.Profiles[newName]; exists {
+			return fmt.Errorf("profile %s already exists", newName)
+		}
+		if p.Keyring {
+			secret, err := keyring.Default.Get(oldName)
+			if err != nil {
+				return fmt.Errorf("failed to read secret from keyring: %w", err)
+			}
+			if err := keyring.Default.Set(newName, secret); err != nil {
+				return fmt.Errorf("failed to store secret in keyring: %w", err)
+			}
+			if err := keyring.Default.Delete(oldName); err != nil {
+				return fmt.Errorf("failed to remove secret from keyring: %w", err)
+			}
+		}
+		p.Name = newName
+		delete(cfg.Profiles, oldName)
+		cfg.Profiles[newName] = p
+		if cfg.Active == oldName {
+			cfg.Active = newName
+		}
+		return nil
+	})
+}
+
+// Redacted returns a copy of the profile with its secrets masked.
+func (p Profile) Redacted() Profile {
+	if p.Pass != "" {
+		p.Pass = redacted
 	}
-	cfg.Profiles[p.Name] = p
-	return save(cfg)
+	if p.Token != "" {
+		p.Token = redacted
+	}
+	if len(p.Headers) > 0 {
+		headers := make(map[string]string, len(p.Headers))
+		for name, v := range p.Headers {
+			if redact.IsSensitiveHeader(name) {
+				v = redact.HeaderValue(name, v)
+			}
+			headers[name] = v
+		}
+		p.Headers = headers
+	}
+	return p
 }
 
+const redacted = redact.Mask
+
 func LoadAll() (map[string]Profile, error) {
 	cfg, err := load()
 	if err != nil {
@@ -90,47 +278,99 @@ func LoadAll() (map[string]Profile, error) {
 }
 
 func SetActive(name string) error {
-	cfg, err := load()
-	if err != nil {
-		return err
-	}
-	if _, ok := cfg.Profiles[name]; !ok {
-		return fmt.Errorf("profile %s does not exist", name)
-	}
-	cfg.Active = name
-	return save(cfg)
+	return update(func(cfg *fileConfig) error {
+		if _, ok := cfg.Profiles[name]; !ok {
+			return fmt.Errorf("profile %s does not exist", name)
+		}
+		cfg.Active = name
+		return nil
+	})
 }
 
+// GetActive returns the active profile with any overrides applied, or nil
+// when no profile is selected.
 func GetActive() (*Profile, error) {
 	cfg, err := load()
 	if err != nil {
 		return nil, err
 	}
-	if cfg.Active == "" {
+	o := currentOverrides()
+
+	var p Profile
+	switch {
+	case o.Profile != "":
+		var ok bool
+		if p, ok = cfg.Profiles[o.Profile]; !ok {
+			return nil, fmt.Errorf("profile %s does not exist", o.Profile)
+		}
+	case cfg.Active != "":
+		var ok bool
+		if p, ok = cfg.Profiles[cfg.Active]; !ok {
+			return nil, fmt.Errorf("active profile %s not found", cfg.Active)
+		}
+	case o.URL != "":
+		// Ad-hoc target given entirely through overrides.
+		p = Profile{Name: "(override)"}
+	default:
 		return nil, nil
 	}
-	p, ok := cfg.Profiles[cfg.Active]
-	if !ok {
-		return nil, fmt.Errorf("active profile %s not found", cfg.Active)
+
+	if o.URL != "" {
+		p.URL = o.URL
+	}
+	if o.Token != "" {
+		p.Token = o.Token
+		p.User = ""
+		p.Pass = ""
+	}
+	if err := ResolveCredentials(&p); err != nil {
+		return nil, err
 	}
 	return &p, nil
 }
 
-// SetActiveOrg stores the selected organization ID.
-func SetActiveOrg(orgID string) error {
+// GetActiveName returns the name of the active profile, honouring a profile
+// override.
+func GetActiveName() (string, error) {
 	cfg, err := load()
 	if err != nil {
-		return err
+		return "", err
 	}
-	cfg.ActiveOrg = orgID
-	return save(cfg)
+	return activeName(cfg), nil
+}
+
+// SetActiveOrg stores the selected organization ID on the active profile.
+func SetActiveOrg(orgID string) error {
+	return update(func(cfg *fileConfig) error {
+		name := activeName(cfg)
+		if name == "" {
+			return fmt.Errorf("no active profile set; use 'gcli config use <name>' first")
+		}
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			return fmt.Errorf("profile %s does not exist", name)
+		}
+		p.OrgID = orgID
+		cfg.Profiles[name] = p
+		return nil
+	})
 }
 
-// GetActiveOrg returns the currently selected organization ID.
+// GetActiveOrg returns the organization ID selected for the active profile.
 func GetActiveOrg() (string, error) {
+	if o := currentOverrides(); o.Org != "" {
+		return o.Org, nil
+	}
 	cfg, err := load()
 	if err != nil {
 		return "", err
 	}
-	return cfg.ActiveOrg, nil
+	return cfg.Profiles[activeName(cfg)].OrgID, nil
+}
+
+func activeName(cfg *fileConfig) string {
+	if o := currentOverrides(); o.Profile != "" {
+		return o.Profile
+	}
+	return cfg.Active
 }
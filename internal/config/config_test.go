@@ -1,9 +1,16 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+
+	"gcli/internal/keyring"
 )
 
 func TestConfigManagement(t *testing.T) {
@@ -70,3 +77,379 @@ func TestConfigManagement(t *testing.T) {
 		t.Errorf("expected org '1', got %s", org)
 	}
 }
+
+func TestResolveCredentials(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "gcli-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	os.Setenv("GCLI_CONFIG_PATH", filepath.Join(tmpDir, "config.yaml"))
+	defer os.Unsetenv("GCLI_CONFIG_PATH")
+
+	oldKeyring := keyring.Default
+	mem := keyring.NewMemory()
+	keyring.Default = mem
+	defer func() { keyring.Default = oldKeyring }()
+
+	mem.Set("kr", "from-keyring")
+	SaveProfile(Profile{Name: "kr", URL: "http://localhost:3000", User: "admin", Keyring: true})
+	SaveProfile(Profile{Name: "cmd", URL: "http://localhost:3000", CredentialCommand: "echo from-command"})
+
+	SetActive("kr")
+	p, err := GetActive()
+	if err != nil {
+		t.Fatalf("GetActive failed: %v", err)
+	}
+	if p.Pass != "from-keyring" || p.Token != "" {
+		t.Errorf("expected password from keyring, got pass=%q token=%q", p.Pass, p.Token)
+	}
+
+	SetActive("cmd")
+	p, err = GetActive()
+	if err != nil {
+		t.Fatalf("GetActive failed: %v", err)
+	}
+	if p.Token != "from-command" {
+		t.Errorf("expected token from credential command, got %q", p.Token)
+	}
+
+	// Secrets must not end up in the config file.
+	profiles, _ := LoadAll()
+	if profiles["kr"].Pass != "" || profiles["cmd"].Token != "" {
+		t.Errorf("resolved secrets were persisted: %+v", profiles)
+	}
+}
+
+func TestKeyringProfiles(t *testing.T) {
+	os.Setenv("GCLI_CONFIG_PATH", filepath.Join(t.TempDir(), "config.yaml"))
+	defer os.Unsetenv("GCLI_CONFIG_PATH")
+
+	oldKeyring := keyring.Default
+	mem := keyring.NewMemory()
+	keyring.Default = mem
+	defer func() { keyring.Default = oldKeyring }()
+
+	mem.Set("token", "glsa_secret")
+	SaveProfile(Profile{Name: "token", URL: "http://localhost:3000", Keyring: true})
+	SaveProfile(Profile{Name: "missing", URL: "http://localhost:3000", Keyring: true})
+
+	// Profiles without a user get the secret as their token.
+	p, _ := GetProfile("token")
+	if err := ResolveCredentials(p); err != nil {
+		t.Fatalf("ResolveCredentials failed: %v", err)
+	}
+	if p.Token != "glsa_secret" || p.Pass != "" {
+		t.Errorf("expected token from keyring, got pass=%q token=%q", p.Pass, p.Token)
+	}
+	p, _ = GetProfile("missing")
+	if err := ResolveCredentials(p); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a profile without a stored secret, got %v", err)
+	}
+
+	SetActive("token")
+	if err := RenameProfile("token", "renamed"); err != nil {
+		t.Fatalf("RenameProfile failed: %v", err)
+	}
+	if _, err := mem.Get("token"); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("expected the old secret to be removed, got %v", err)
+	}
+	if s, _ := mem.Get("renamed"); s != "glsa_secret" {
+		t.Errorf("expected the secret under the new name, got %q", s)
+	}
+	if name, _ := GetActiveName(); name != "renamed" {
+		t.Errorf("expected the active profile to follow the rename, got %q", name)
+	}
+	if err := RenameProfile("missing", "renamed"); err == nil {
+		t.Errorf("expected renaming onto an existing profile to fail")
+	}
+
+	if err := DeleteProfile("renamed"); err != nil {
+		t.Fatalf("DeleteProfile failed: %v", err)
+	}
+	if _, err := mem.Get("renamed"); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("expected the secret to be removed with the profile, got %v", err)
+	}
+	if name, _ := GetActiveName(); name != "" {
+		t.Errorf("expected no active profile after deleting it, got %q", name)
+	}
+	// A secret that is already gone does not block deleting the profile.
+	if err := DeleteProfile("missing"); err != nil {
+		t.Errorf("DeleteProfile without a stored secret failed: %v", err)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "gcli-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	os.Setenv("GCLI_CONFIG_PATH", filepath.Join(tmpDir, "config.yaml"))
+	defer os.Unsetenv("GCLI_CONFIG_PATH")
+
+	SaveProfile(Profile{Name: "prod", URL: "http://prod:3000", User: "admin", Pass: "admin"})
+	SaveProfile(Profile{Name: "staging", URL: "http://staging:3000", User: "admin", Pass: "admin"})
+	SetActive("prod")
+	SetActiveOrg("1")
+
+	os.Setenv("GCLI_PROFILE", "staging")
+	os.Setenv("GCLI_ORG", "2")
+	defer os.Unsetenv("GCLI_PROFILE")
+	defer os.Unsetenv("GCLI_ORG")
+
+	p, err := GetActive()
+	if err != nil {
+		t.Fatalf("GetActive failed: %v", err)
+	}
+	if p.Name != "staging" {
+		t.Errorf("expected env to select 'staging', got %s", p.Name)
+	}
+	if org, _ := GetActiveOrg(); org != "2" {
+		t.Errorf("expected env org '2', got %s", org)
+	}
+
+	// Explicit overrides win over the environment.
+	SetOverrides(Overrides{Profile: "prod", URL: "http://other:3000", Token: "tok"})
+	defer SetOverrides(Overrides{})
+	p, err = GetActive()
+	if err != nil {
+		t.Fatalf("GetActive failed: %v", err)
+	}
+	if p.Name != "prod" || p.URL != "http://other:3000" || p.Token != "tok" || p.Pass != "" {
+		t.Errorf("overrides not applied: %+v", p)
+	}
+
+	// The config file itself is untouched.
+	SetOverrides(Overrides{})
+	os.Unsetenv("GCLI_PROFILE")
+	p, _ = GetActive()
+	if p.Name != "prod" || p.URL != "http://prod:3000" {
+		t.Errorf("expected stored active profile 'prod', got %+v", p)
+	}
+}
+
+func TestPerProfileActiveOrg(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "gcli-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpCfg := filepath.Join(tmpDir, "config.yaml")
+	os.Setenv("GCLI_CONFIG_PATH", tmpCfg)
+	defer os.Unsetenv("GCLI_CONFIG_PATH")
+
+	// A file written before organizations were stored per profile.
+	legacy := `active: prod
+active_org: "4"
+profiles:
+  prod:
+    name: prod
+    url: http://prod:3000
+  staging:
+    name: staging
+    url: http://staging:3000
+`
+	if err := os.WriteFile(tmpCfg, []byte(legacy), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	if org, _ := GetActiveOrg(); org != "4" {
+		t.Errorf("expected legacy org '4' on active profile, got %q", org)
+	}
+
+	SetActive("staging")
+	if org, _ := GetActiveOrg(); org != "" {
+		t.Errorf("expected no org for 'staging', got %q", org)
+	}
+	SetActiveOrg("7")
+
+	SetActive("prod")
+	if org, _ := GetActiveOrg(); org != "4" {
+		t.Errorf("expected 'prod' to keep org '4', got %q", org)
+	}
+
+	profiles, _ := LoadAll()
+	if profiles["staging"].OrgID != "7" {
+		t.Errorf("expected 'staging' org '7', got %q", profiles["staging"].OrgID)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	p := Profile{
+		Name:  "prod",
+		Pass:  "hunter2",
+		Token: "glsa_secret",
+		Headers: map[string]string{
+			"CF-Access-Client-Id":     "client-id",
+			"CF-Access-Client-Secret": "topsecret",
+			"Proxy-Authorization":     "Basic dXNlcjpwYXNz",
+			"X-Scope-OrgID":           "tenant-1",
+		},
+	}
+	r := p.Redacted()
+	want := map[string]string{
+		"CF-Access-Client-Id":     "client-id",
+		"CF-Access-Client-Secret": "********",
+		"Proxy-Authorization":     "Basic ********",
+		"X-Scope-OrgID":           "tenant-1",
+	}
+	if r.Pass != "********" || r.Token != "********" {
+		t.Errorf("expected password and token masked, got pass=%q token=%q", r.Pass, r.Token)
+	}
+	for name, v := range want {
+		if r.Headers[name] != v {
+			t.Errorf("header %s: expected %q, got %q", name, v, r.Headers[name])
+		}
+	}
+	if p.Headers["CF-Access-Client-Secret"] != "topsecret" {
+		t.Errorf("Redacted modified the original profile's headers")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "gcli-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpCfg := filepath.Join(tmpDir, "config.yaml")
+	os.Setenv("GCLI_CONFIG_PATH", tmpCfg)
+	defer os.Unsetenv("GCLI_CONFIG_PATH")
+
+	legacy := []byte(`active: prod
+active_org: 3
+profiles:
+  prod:
+    name: prod
+    url: http://prod:3000
+    user: admin
+    pass: hunter2
+    headers:
+      CF-Access-Client-Secret: topsecret
+`)
+	os.WriteFile(tmpCfg, legacy, 0o600)
+
+	plan, err := Migrate(true)
+	if err != nil {
+		t.Fatalf("Migrate dry run failed: %v", err)
+	}
+	if plan.From != 1 || plan.To != CurrentVersion || len(plan.Steps) != 1 {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+	if strings.Contains(string(plan.Result), "hunter2") || strings.Contains(string(plan.Result), "topsecret") {
+		t.Errorf("dry run result leaked a secret: %s", plan.Result)
+	}
+	if data, _ := os.ReadFile(tmpCfg); string(data) != string(legacy) {
+		t.Errorf("dry run modified the config file")
+	}
+
+	// Any load migrates the file and keeps a backup.
+	if org, _ := GetActiveOrg(); org != "3" {
+		t.Errorf("expected migrated org '3', got %q", org)
+	}
+	backup, err := os.ReadFile(tmpCfg + ".v1.bak")
+	if err != nil || string(backup) != string(legacy) {
+		t.Errorf("expected backup of the previous file, got %q (%v)", backup, err)
+	}
+	data, _ := os.ReadFile(tmpCfg)
+	if !strings.Contains(string(data), "version: 2") || strings.Contains(string(data), "active_org") {
+		t.Errorf("config not rewritten in current schema: %s", data)
+	}
+
+	plan, err = Migrate(false)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(plan.Steps) != 0 {
+		t.Errorf("expected no further migrations, got %v", plan.Steps)
+	}
+
+	os.WriteFile(tmpCfg, []byte("version: 99\n"), 0o600)
+	if _, err := LoadAll(); err == nil {
+		t.Errorf("expected error for a newer config version")
+	}
+}
+
+// TestConcurrentWrites runs many writers in goroutines and in separate
+// processes; without locking some of their profiles would be lost.
+func TestConcurrentWrites(t *testing.T) {
+	if os.Getenv("GCLI_CONCURRENT_WRITER") != "" {
+		return
+	}
+	tmpDir, err := os.MkdirTemp("", "gcli-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpCfg := filepath.Join(tmpDir, "config.yaml")
+	os.Setenv("GCLI_CONFIG_PATH", tmpCfg)
+	defer os.Unsetenv("GCLI_CONFIG_PATH")
+
+	SaveProfile(Profile{Name: "base", URL: "http://localhost:3000"})
+	SetActive("base")
+
+	const goroutines, processes, writes = 8, 4, 10
+
+	var cmds []*exec.Cmd
+	for i := 0; i < processes; i++ {
+		c := exec.Command(os.Args[0], "-test.run=TestConcurrentWriterProcess")
+		c.Env = append(os.Environ(), fmt.Sprintf("GCLI_CONCURRENT_WRITER=proc%d", i))
+		if err := c.Start(); err != nil {
+			t.Fatalf("failed to start writer process: %v", err)
+		}
+		cmds = append(cmds, c)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			writeProfiles(t, fmt.Sprintf("goroutine%d", i), writes)
+		}(i)
+	}
+	wg.Wait()
+	for _, c := range cmds {
+		if err := c.Wait(); err != nil {
+			t.Errorf("writer process failed: %v", err)
+		}
+	}
+
+	profiles, err := LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if want := 1 + (goroutines+processes)*writes; len(profiles) != want {
+		t.Errorf("expected %d profiles, got %d", want, len(profiles))
+	}
+	if _, err := GetActiveOrg(); err != nil {
+		t.Errorf("config corrupted: %v", err)
+	}
+}
+
+// TestConcurrentWriterProcess is the body of the writer processes started by
+// TestConcurrentWrites.
+func TestConcurrentWriterProcess(t *testing.T) {
+	prefix := os.Getenv("GCLI_CONCURRENT_WRITER")
+	if prefix == "" {
+		t.Skip("helper process for TestConcurrentWrites")
+	}
+	writeProfiles(t, prefix, 10)
+}
+
+func writeProfiles(t *testing.T, prefix string, n int) {
+	for j := 0; j < n; j++ {
+		if err := SaveProfile(Profile{Name: fmt.Sprintf("%s-%d", prefix, j), URL: "http://localhost:3000"}); err != nil {
+			t.Errorf("SaveProfile failed: %v", err)
+		}
+		if err := SetActiveOrg(fmt.Sprint(j)); err != nil {
+			t.Errorf("SetActiveOrg failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"gcli/internal/keyring"
+)
+
+// ResolveCredentials fills in the secret of a profile that keeps it outside
+// the config file, either in the OS keyring or behind a credential command.
+// Profiles with a user receive the secret as their password, all others as
+// their token.
+func ResolveCredentials(p *Profile) error {
+	if p.Pass != "" || p.Token != "" {
+		return nil
+	}
+
+	var secret string
+	switch {
+	case p.Keyring:
+		s, err := keyring.Default.Get(p.Name)
+		if err != nil {
+			return fmt.Errorf("failed to read secret for profile %s from keyring: %w", p.Name, err)
+		}
+		secret = s
+	case p.CredentialCommand != "":
+		s, err := runCredentialCommand(p.CredentialCommand)
+		if err != nil {
+			return fmt.Errorf("credential command for profile %s failed: %w", p.Name, err)
+		}
+		secret = s
+	default:
+		return nil
+	}
+
+	if p.User != "" {
+		p.Pass = secret
+	} else {
+		p.Token = secret
+	}
+	return nil
+}
+
+func runCredentialCommand(command string) (string, error) {
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command("sh", "-c", command)
+	}
+	// Let the command prompt on the terminal, e.g. for a GPG passphrase.
+	c.Stdin = os.Stdin
+	c.Stderr = os.Stderr
+	out, err := c.Output()
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimRight(string(out), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("command printed no secret")
+	}
+	return secret, nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// processLock serializes config access between goroutines; the file lock
+// below does the same between processes.
+var processLock sync.Mutex
+
+// lockConfig takes an exclusive advisory lock on path+".lock" and returns the
+// function releasing it.
+func lockConfig(path string) (func(), error) {
+	processLock.Lock()
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		processLock.Unlock()
+		return nil, fmt.Errorf("failed to open config lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		processLock.Unlock()
+		return nil, fmt.Errorf("failed to lock config: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+		processLock.Unlock()
+	}, nil
+}
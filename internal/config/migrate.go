@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config schema version written by this gcli. Files
+// without a version field are version 1.
+const CurrentVersion = 2
+
+// migration upgrades a raw config document from version from to from+1.
+type migration struct {
+	from        int
+	description string
+	apply       func(doc map[string]interface{}) error
+}
+
+// migrations must stay ordered by from, one step per version.
+var migrations = []migration{
+	{
+		from:        1,
+		description: "move the global active_org into the active profile's org_id",
+		apply:       migrateActiveOrgToProfile,
+	},
+}
+
+// migrateActiveOrgToProfile moves the single global organization into the
+// profile it was selected on, since an org ID is meaningless on another
+// Grafana instance.
+func migrateActiveOrgToProfile(doc map[string]interface{}) error {
+	raw, ok := doc["active_org"]
+	delete(doc, "active_org")
+	if !ok || raw == nil || fmt.Sprint(raw) == "" {
+		return nil
+	}
+	active, _ := doc["active"].(string)
+	profiles, _ := doc["profiles"].(map[string]interface{})
+	p, ok := profiles[active].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if existing, ok := p["org_id"]; !ok || fmt.Sprint(existing) == "" {
+		p["org_id"] = fmt.Sprint(raw)
+	}
+	return nil
+}
+
+// upgrade parses a config file and runs the migrations it needs. It returns
+// the config, the version the file had and the descriptions of the applied
+// migrations.
+func upgrade(data []byte) (*fileConfig, int, []string, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	from := 1
+	if v, ok := doc["version"]; ok {
+		n, ok := v.(int)
+		if !ok {
+			return nil, 0, nil, fmt.Errorf("failed to parse config: invalid version %v", v)
+		}
+		from = n
+	}
+	if from > CurrentVersion {
+		return nil, 0, nil, fmt.Errorf("config file version %d is newer than this gcli supports (%d); please upgrade gcli", from, CurrentVersion)
+	}
+
+	var steps []string
+	version := from
+	for _, m := range migrations {
+		if m.from != version {
+			continue
+		}
+		if err := m.apply(doc); err != nil {
+			return nil, 0, nil, fmt.Errorf("config migration to version %d failed: %w", m.from+1, err)
+		}
+		version = m.from + 1
+		doc["version"] = version
+		steps = append(steps, m.description)
+	}
+	if version != CurrentVersion {
+		return nil, 0, nil, fmt.Errorf("no config migration from version %d", version)
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	var cfg fileConfig
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]Profile{}
+	}
+	cfg.Version = CurrentVersion
+	return &cfg, from, steps, nil
+}
+
+// writeMigrated keeps the previous file as a backup next to the config and
+// then saves cfg in the current schema. It returns the backup path.
+func writeMigrated(path string, data []byte, from int, cfg *fileConfig) (string, error) {
+	backup := fmt.Sprintf("%s.v%d.bak", path, from)
+	if err := writeFileAtomic(backup, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to back up config before migration: %w", err)
+	}
+	return backup, save(path, cfg)
+}
+
+// MigrationPlan describes what Migrate did or would do to the config file.
+type MigrationPlan struct {
+	Path  string
+	From  int
+	To    int
+	Steps []string
+	// Result is the migrated file with secrets masked.
+	Result []byte
+	// Backup is where the previous file was saved; empty for dry runs.
+	Backup string
+}
+
+// Migrate upgrades the config file to CurrentVersion. With dryRun it only
+// reports the changes without writing anything.
+func Migrate(dryRun bool) (*MigrationPlan, error) {
+	path, err := configFilePath()
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	plan := &MigrationPlan{Path: path, From: CurrentVersion, To: CurrentVersion}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return plan, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, from, steps, err := upgrade(data)
+	if err != nil {
+		return nil, err
+	}
+	plan.From = from
+	plan.Steps = steps
+
+	masked := fileConfig{Version: cfg.Version, Active: cfg.Active, Profiles: map[string]Profile{}}
+	for name, p := range cfg.Profiles {
+		masked.Profiles[name] = p.Redacted()
+	}
+	if plan.Result, err = yaml.Marshal(&masked); err != nil {
+		return nil, err
+	}
+
+	if dryRun || len(steps) == 0 {
+		return plan, nil
+	}
+	if plan.Backup, err = writeMigrated(path, data, from, cfg); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import "os"
+
+// Overrides select the target of a single invocation without writing the
+// config file. Empty fields fall back to the GCLI_PROFILE, GCLI_ORG, GCLI_URL
+// and GCLI_TOKEN environment variables.
+type Overrides struct {
+	Profile string
+	Org     string
+	URL     string
+	Token   string
+}
+
+var overrides Overrides
+
+// SetOverrides installs the overrides used by GetActive and GetActiveOrg.
+func SetOverrides(o Overrides) {
+	overrides = o
+}
+
+func currentOverrides() Overrides {
+	o := overrides
+	if o.Profile == "" {
+		o.Profile = os.Getenv("GCLI_PROFILE")
+	}
+	if o.Org == "" {
+		o.Org = os.Getenv("GCLI_ORG")
+	}
+	if o.URL == "" {
+		o.URL = os.Getenv("GCLI_URL")
+	}
+	if o.Token == "" {
+		o.Token = os.Getenv("GCLI_TOKEN")
+	}
+	return o
+}
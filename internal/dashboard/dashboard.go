@@ -0,0 +1,162 @@
+// Package dashboard handles dashboard models outside of Grafana: stripping
+// instance-specific fields, stable JSON encoding and the directory layout used
+// by dash pull and dash push.
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// InstanceFields are set by Grafana on every save and differ between
+// instances, so they are not kept in files.
+var InstanceFields = []string{"id", "version"}
+
+// FolderFile is the name of the file describing the Grafana folder of a
+// directory.
+const FolderFile = ".folder.json"
+
+// Strip returns a copy of model without the given top-level fields.
+func Strip(model map[string]interface{}, fields ...string) map[string]interface{} {
+	out := make(map[string]interface{}, len(model))
+	for k, v := range model {
+		out[k] = v
+	}
+	for _, f := range fields {
+		delete(out, f)
+	}
+	return out
+}
+
+// Marshal encodes v as indented JSON ending in a newline. Object keys are
+// sorted and HTML characters are not escaped, so the output is stable and
+// diffs well.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FileName returns a lowercase, file system safe name for a title, or ""
+// when the title has no letters or digits.
+func FileName(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// Folder identifies a Grafana folder.
+type Folder struct {
+	UID   string `json:"uid,omitempty"`
+	Title string `json:"title"`
+}
+
+// File is a dashboard read from a directory tree.
+type File struct {
+	// Path is relative to the tree root, with forward slashes.
+	Path string
+	// Folders is the chain of folders containing the dashboard, outermost
+	// first; it is empty for the General folder.
+	Folders []Folder
+	Model   map[string]interface{}
+}
+
+// UID returns the uid of the dashboard model, or "".
+func (f *File) UID() string {
+	uid, _ := f.Model["uid"].(string)
+	return uid
+}
+
+// ReadDir reads every dashboard below dir. Each subdirectory is a folder,
+// described by its FolderFile or else titled after the directory. Hidden
+// files and directories are skipped.
+func ReadDir(dir string) ([]File, error) {
+	folders := map[string][]Folder{".": nil}
+	var files []File
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if rel == "." {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		parent := folders[filepath.Dir(rel)]
+
+		if d.IsDir() {
+			folder := Folder{Title: d.Name()}
+			data, err := os.ReadFile(filepath.Join(path, FolderFile))
+			if err == nil {
+				if err := json.Unmarshal(data, &folder); err != nil {
+					return fmt.Errorf("%s: %w", filepath.Join(path, FolderFile), err)
+				}
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			folders[rel] = append(append([]Folder(nil), parent...), folder)
+			return nil
+		}
+		if filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var model map[string]interface{}
+		if err := json.Unmarshal(data, &model); err != nil {
+			return fmt.Errorf("%s: invalid dashboard JSON: %w", path, err)
+		}
+		files = append(files, File{Path: filepath.ToSlash(rel), Folders: parent, Model: model})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// WriteFile writes v to path with Marshal, creating parent directories. It
+// reports whether the file changed; an identical file is left untouched.
+func WriteFile(path string, v interface{}) (bool, error) {
+	data, err := Marshal(v)
+	if err != nil {
+		return false, err
+	}
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,124 @@
+package dashboard
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	model := map[string]interface{}{"title": "A & B", "uid": "x", "id": 3.0, "version": 7.0, "panels": []interface{}{"b", "a"}}
+	data, err := Marshal(Strip(model, InstanceFields...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"panels\": [\n    \"b\",\n    \"a\"\n  ],\n  \"title\": \"A & B\",\n  \"uid\": \"x\"\n}\n"
+	if string(data) != want {
+		t.Errorf("unexpected encoding:\n%s", data)
+	}
+	if _, ok := model["id"]; !ok {
+		t.Error("Strip must not modify its argument")
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := map[string]string{
+		"Kubernetes / Pods": "kubernetes-pods",
+		"CPU (%)":           "cpu",
+		"Übersicht 2":       "übersicht-2",
+		"!!!":               "",
+	}
+	for title, want := range tests {
+		if got := FileName(title); got != want {
+			t.Errorf("FileName(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestReadWriteDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path string, v interface{}) {
+		if _, err := WriteFile(filepath.Join(dir, path), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("home.json", map[string]interface{}{"uid": "home"})
+	write("ops/.folder.json", Folder{UID: "ops", Title: "Operations"})
+	write("ops/db/cpu.json", map[string]interface{}{"uid": "cpu"})
+	write(".git/config.json", map[string]interface{}{"uid": "ignored"})
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("notes"), 0644)
+
+	files, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Path+" "+f.UID())
+	}
+	if want := []string{"home.json home", "ops/db/cpu.json cpu"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	wantFolders := []Folder{{UID: "ops", Title: "Operations"}, {Title: "db"}}
+	if !reflect.DeepEqual(files[1].Folders, wantFolders) {
+		t.Errorf("expected folders %v, got %v", wantFolders, files[1].Folders)
+	}
+
+	changed, err := WriteFile(filepath.Join(dir, "home.json"), map[string]interface{}{"uid": "home"})
+	if err != nil || changed {
+		t.Errorf("expected an identical write to be skipped, got %v (%v)", changed, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := map[string]interface{}{
+		"id": 1.0, "version": 3.0, "iteration": 111.0, "title": "Nodes",
+		"panels": []interface{}{
+			map[string]interface{}{"title": "CPU", "targets": []interface{}{map[string]interface{}{"expr": "rate(a[5m])"}}},
+			map[string]interface{}{"title": "Disk"},
+		},
+	}
+	new := map[string]interface{}{
+		"id": 2.0, "version": 9.0, "iteration": 222.0, "title": "Nodes", "refresh": "1m",
+		"panels": []interface{}{
+			map[string]interface{}{"title": "CPU", "targets": []interface{}{map[string]interface{}{"expr": "rate(b[5m])"}}},
+		},
+	}
+	changes := Diff(old, new)
+	want := []Change{
+		{Kind: Changed, Path: "panels[0].targets[0].expr", Panel: "CPU", Old: "rate(a[5m])", New: "rate(b[5m])"},
+		{Kind: Removed, Path: "panels[1]", Panel: "Disk", Old: map[string]interface{}{"title": "Disk"}},
+		{Kind: Added, Path: "refresh", New: "1m"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("expected %+v, got %+v", want, changes)
+	}
+	if len(Diff(old, Strip(old, "id"))) != 0 {
+		t.Error("expected no changes when only ignored fields differ")
+	}
+
+	var buf bytes.Buffer
+	WriteDiff(&buf, changes[:1])
+	if got := buf.String(); got != "~ panels[0].targets[0].expr  (panel \"CPU\")\n    - \"rate(a[5m])\"\n    + \"rate(b[5m])\"\n" {
+		t.Errorf("unexpected diff output:\n%s", got)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	base := map[string]interface{}{"title": "A", "refresh": "1m", "panels": []interface{}{map[string]interface{}{"title": "CPU", "type": "graph"}}}
+	mine := map[string]interface{}{"title": "B", "refresh": "5m", "panels": []interface{}{map[string]interface{}{"title": "CPU", "type": "timeseries"}}}
+	theirs := map[string]interface{}{"title": "A", "refresh": "5m", "panels": []interface{}{map[string]interface{}{"title": "CPU (all)", "type": "graph"}}, "tags": []interface{}{"x"}}
+
+	got := Conflicts(Diff(base, mine), Diff(base, theirs))
+	if len(got) != 0 {
+		t.Errorf("expected no conflicts for separate and identical changes, got %v", got)
+	}
+
+	theirs["panels"] = []interface{}{}
+	got = Conflicts(Diff(base, mine), Diff(base, theirs))
+	if want := []string{"panels[0].type"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
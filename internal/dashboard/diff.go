@@ -0,0 +1,190 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DiffIgnored are the fields left out when comparing dashboards. Grafana
+// changes them on every save, so they never mean the content differs.
+var DiffIgnored = []string{"id", "version", "iteration"}
+
+// Change kinds.
+const (
+	Added   = "+"
+	Removed = "-"
+	Changed = "~"
+)
+
+// Change is one difference between two dashboard models.
+type Change struct {
+	Kind string
+	// Path locates the value, e.g. panels[2].targets[0].expr.
+	Path string
+	// Panel is the title of the innermost panel containing the value, or
+	// "" outside of panels.
+	Panel string
+	Old   interface{}
+	New   interface{}
+}
+
+// Diff compares two dashboard models, ignoring DiffIgnored. Objects are
+// compared key by key and arrays element by element, so panels keep their
+// order: a moved panel shows up as changes at both positions.
+func Diff(old, new map[string]interface{}) []Change {
+	var changes []Change
+	diffValue(&changes, "", "", Strip(old, DiffIgnored...), Strip(new, DiffIgnored...))
+	return changes
+}
+
+func diffValue(changes *[]Change, path, panel string, old, new interface{}) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		n, ok := new.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(o)+len(n))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		for k := range n {
+			if _, ok := o[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ov, inOld := o[k]
+			nv, inNew := n[k]
+			p := joinKey(path, k)
+			switch {
+			case !inOld:
+				*changes = append(*changes, Change{Kind: Added, Path: p, Panel: panel, New: nv})
+			case !inNew:
+				*changes = append(*changes, Change{Kind: Removed, Path: p, Panel: panel, Old: ov})
+			default:
+				diffValue(changes, p, panel, ov, nv)
+			}
+		}
+		return
+	case []interface{}:
+		n, ok := new.([]interface{})
+		if !ok {
+			break
+		}
+		isPanels := path == "panels" || strings.HasSuffix(path, ".panels")
+		for i := 0; i < len(o) || i < len(n); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			elemPanel := panel
+			switch {
+			case i >= len(o):
+				if isPanels {
+					elemPanel = panelTitle(n[i], panel)
+				}
+				*changes = append(*changes, Change{Kind: Added, Path: p, Panel: elemPanel, New: n[i]})
+			case i >= len(n):
+				if isPanels {
+					elemPanel = panelTitle(o[i], panel)
+				}
+				*changes = append(*changes, Change{Kind: Removed, Path: p, Panel: elemPanel, Old: o[i]})
+			default:
+				if isPanels {
+					elemPanel = panelTitle(n[i], panelTitle(o[i], panel))
+				}
+				diffValue(changes, p, elemPanel, o[i], n[i])
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Kind: Changed, Path: path, Panel: panel, Old: old, New: new})
+	}
+}
+
+func panelTitle(v interface{}, def string) string {
+	if m, ok := v.(map[string]interface{}); ok {
+		if title, ok := m["title"].(string); ok && title != "" {
+			return title
+		}
+	}
+	return def
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func joinKey(path, key string) string {
+	if !identifier.MatchString(key) {
+		quoted, _ := json.Marshal(key)
+		return fmt.Sprintf("%s[%s]", path, quoted)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// WriteDiff prints changes, one entry per change:
+//
+//	~ panels[2].targets[0].expr  (panel "CPU")
+//	    - "rate(a[5m])"
+//	    + "rate(b[5m])"
+func WriteDiff(w io.Writer, changes []Change) {
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s %s", c.Kind, c.Path)
+		if c.Panel != "" {
+			fmt.Fprintf(w, "  (panel %q)", c.Panel)
+		}
+		fmt.Fprintln(w)
+		if c.Kind != Added {
+			fmt.Fprintf(w, "    - %s\n", compact(c.Old))
+		}
+		if c.Kind != Removed {
+			fmt.Fprintf(w, "    + %s\n", compact(c.New))
+		}
+	}
+}
+
+func compact(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// Conflicts returns the paths changed on both sides of a three-way
+// comparison, given the changes from a common base to each side. Paths
+// conflict when they are equal or one contains the other, unless both sides
+// made the same change.
+func Conflicts(mine, theirs []Change) []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, m := range mine {
+		for _, t := range theirs {
+			if !overlaps(m.Path, t.Path) || (m.Path == t.Path && m.Kind == t.Kind && reflect.DeepEqual(m.New, t.New)) {
+				continue
+			}
+			if !seen[m.Path] {
+				seen[m.Path] = true
+				paths = append(paths, m.Path)
+			}
+		}
+	}
+	return paths
+}
+
+func overlaps(a, b string) bool {
+	return a == b || contains(a, b) || contains(b, a)
+}
+
+// contains reports whether path inner lies inside path outer.
+func contains(outer, inner string) bool {
+	return strings.HasPrefix(inner, outer+".") || strings.HasPrefix(inner, outer+"[")
+}
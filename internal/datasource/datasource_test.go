@@ -1,26 +1,19 @@
 package datasource
 
 import (
-	"fmt"
 	"gcli/internal/config"
-	"net/http"
-	"net/http/httptest"
+	"gcli/internal/grafanatest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 )
 
 func TestResolveID(t *testing.T) {
-	// Mock Grafana API
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/api/datasources" {
-			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprintln(w, `[{"id":1, "name":"Prometheus"}, {"id":2, "name":"InfluxDB"}]`)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-	}))
-	defer ts.Close()
+	srv := grafanatest.NewServer()
+	defer srv.Close()
+	promID := srv.AddDatasource(grafanatest.MainOrgID, grafanatest.Datasource{Name: "Prometheus", Type: "prometheus"})
+	influxID := srv.AddDatasource(grafanatest.MainOrgID, grafanatest.Datasource{Name: "InfluxDB", Type: "influxdb"})
 
 	// Setup temp config
 	tmpDir, _ := os.MkdirTemp("", "gcli-ds-test-*")
@@ -29,12 +22,7 @@ func TestResolveID(t *testing.T) {
 	os.Setenv("GCLI_CONFIG_PATH", tmpCfg)
 	defer os.Unsetenv("GCLI_CONFIG_PATH")
 
-	config.SaveProfile(config.Profile{
-		Name: "test",
-		URL:  ts.URL,
-		User: "admin",
-		Pass: "admin",
-	})
+	config.SaveProfile(srv.Profile("test"))
 	config.SetActive("test")
 
 	tests := []struct {
@@ -42,9 +30,9 @@ func TestResolveID(t *testing.T) {
 		expected int
 		wantErr  bool
 	}{
-		{"Prometheus", 1, false},
-		{"1", 1, false},
-		{"2", 2, false},
+		{"Prometheus", int(promID), false},
+		{strconv.FormatInt(promID, 10), int(promID), false},
+		{strconv.FormatInt(influxID, 10), int(influxID), false},
 		{"Unknown", 0, true},
 	}
 
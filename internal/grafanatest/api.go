@@ -0,0 +1,675 @@
+package grafanatest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	handle("GET", "/api/health", getHealth)
+	handle("GET", "/api/user", getUser)
+	handle("GET", "/api/org", getCurrentOrg)
+
+	handle("GET", "/api/orgs", listOrgs)
+	handle("POST", "/api/orgs", createOrg)
+	handle("GET", "/api/orgs/name/:name", getOrgByName)
+	handle("GET", "/api/orgs/:id", getOrg)
+	handle("PUT", "/api/orgs/:id", updateOrg)
+	handle("DELETE", "/api/orgs/:id", deleteOrg)
+
+	handle("GET", "/api/datasources", listDatasources)
+	handle("POST", "/api/datasources", createDatasource)
+	handle("GET", "/api/datasources/uid/:uid", getDatasource)
+	handle("GET", "/api/datasources/name/:name", getDatasource)
+	handle("GET", "/api/datasources/:id", getDatasource)
+	handle("PUT", "/api/datasources/:id", updateDatasource)
+	handle("DELETE", "/api/datasources/uid/:uid", deleteDatasource)
+	handle("DELETE", "/api/datasources/name/:name", deleteDatasource)
+	handle("DELETE", "/api/datasources/:id", deleteDatasource)
+
+	handle("GET", "/api/folders", listFolders)
+	handle("POST", "/api/folders", createFolder)
+	handle("GET", "/api/folders/:uid", getFolder)
+	handle("DELETE", "/api/folders/:uid", deleteFolder)
+
+	handle("GET", "/api/search", search)
+	handle("POST", "/api/dashboards/db", saveDashboard)
+	handle("GET", "/api/dashboards/uid/:uid", getDashboard)
+	handle("DELETE", "/api/dashboards/uid/:uid", deleteDashboard)
+	handle("GET", "/api/dashboards/uid/:uid/versions", listDashboardVersions)
+	handle("GET", "/api/dashboards/uid/:uid/versions/:version", getDashboardVersion)
+	handle("POST", "/api/dashboards/uid/:uid/restore", restoreDashboard)
+}
+
+func getHealth(s *Server, w http.ResponseWriter, r *request) {
+	writeJSON(w, http.StatusOK, map[string]string{"commit": "grafanatest", "database": "ok", "version": s.Version})
+}
+
+func getUser(s *Server, w http.ResponseWriter, r *request) {
+	login := s.User
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		login = "sa-grafanatest"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id": 1, "login": login, "email": login + "@localhost", "name": login,
+		"isGrafanaAdmin": true, "orgId": r.org.id,
+	})
+}
+
+func getCurrentOrg(s *Server, w http.ResponseWriter, r *request) {
+	writeJSON(w, http.StatusOK, orgJSON(r.org))
+}
+
+func orgJSON(o *org) map[string]interface{} {
+	return map[string]interface{}{"id": o.id, "name": o.name}
+}
+
+func listOrgs(s *Server, w http.ResponseWriter, r *request) {
+	ids := make([]int64, 0, len(s.orgs))
+	for id := range s.orgs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	list := make([]interface{}, len(ids))
+	for i, id := range ids {
+		list[i] = orgJSON(s.orgs[id])
+	}
+	writeJSON(w, http.StatusOK, paginate(list, r, "perpage", 1000))
+}
+
+func (s *Server) findOrg(w http.ResponseWriter, idParam string) (*org, bool) {
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if o, ok := s.orgs[id]; err == nil && ok {
+		return o, true
+	}
+	writeError(w, http.StatusNotFound, "Organization not found")
+	return nil, false
+}
+
+func (s *Server) orgByName(name string) *org {
+	for _, o := range s.orgs {
+		if strings.EqualFold(o.name, name) {
+			return o
+		}
+	}
+	return nil
+}
+
+func getOrg(s *Server, w http.ResponseWriter, r *request) {
+	if o, ok := s.findOrg(w, r.params["id"]); ok {
+		writeJSON(w, http.StatusOK, orgJSON(o))
+	}
+}
+
+func getOrgByName(s *Server, w http.ResponseWriter, r *request) {
+	if o := s.orgByName(r.params["name"]); o != nil {
+		writeJSON(w, http.StatusOK, orgJSON(o))
+		return
+	}
+	writeError(w, http.StatusNotFound, "Organization not found")
+}
+
+func createOrg(s *Server, w http.ResponseWriter, r *request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := r.decode(&body); err != nil || body.Name == "" {
+		writeError(w, http.StatusBadRequest, "bad request data")
+		return
+	}
+	if s.orgByName(body.Name) != nil {
+		writeError(w, http.StatusConflict, "Organization name taken")
+		return
+	}
+	o := s.addOrg(body.Name)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Organization created", "orgId": o.id})
+}
+
+func updateOrg(s *Server, w http.ResponseWriter, r *request) {
+	o, ok := s.findOrg(w, r.params["id"])
+	if !ok {
+		return
+	}
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := r.decode(&body); err != nil || body.Name == "" {
+		writeError(w, http.StatusBadRequest, "bad request data")
+		return
+	}
+	if other := s.orgByName(body.Name); other != nil && other != o {
+		writeError(w, http.StatusConflict, "Organization name taken")
+		return
+	}
+	o.name = body.Name
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Organization updated"})
+}
+
+func deleteOrg(s *Server, w http.ResponseWriter, r *request) {
+	o, ok := s.findOrg(w, r.params["id"])
+	if !ok {
+		return
+	}
+	delete(s.orgs, o.id)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Organization deleted"})
+}
+
+func listDatasources(s *Server, w http.ResponseWriter, r *request) {
+	writeJSON(w, http.StatusOK, sortedDatasources(r.org))
+}
+
+// findDatasource looks a datasource up by the id, uid or name parameter.
+func findDatasource(r *request) (int64, map[string]interface{}) {
+	for id, ds := range r.org.datasources {
+		switch {
+		case r.params["id"] != "" && strconv.FormatInt(id, 10) == r.params["id"],
+			r.params["uid"] != "" && ds["uid"] == r.params["uid"],
+			r.params["name"] != "" && ds["name"] == r.params["name"]:
+			return id, ds
+		}
+	}
+	return 0, nil
+}
+
+func getDatasource(s *Server, w http.ResponseWriter, r *request) {
+	if _, ds := findDatasource(r); ds != nil {
+		writeJSON(w, http.StatusOK, ds)
+		return
+	}
+	writeError(w, http.StatusNotFound, "Data source not found")
+}
+
+// saveDatasource stores a datasource under id, or a new ID when id is 0.
+// Secrets are reduced to secureJsonFields, as Grafana never returns them.
+func (s *Server) saveDatasource(o *org, id int64, ds map[string]interface{}) int64 {
+	if id == 0 {
+		id = s.newID()
+	}
+	if uid, _ := ds["uid"].(string); uid == "" {
+		ds["uid"] = s.newUID()
+	}
+	if secure, ok := ds["secureJsonData"].(map[string]interface{}); ok {
+		fields := map[string]bool{}
+		for k := range secure {
+			fields[k] = true
+		}
+		ds["secureJsonFields"] = fields
+		delete(ds, "secureJsonData")
+	}
+	ds["id"] = id
+	ds["orgId"] = o.id
+	o.datasources[id] = ds
+	return id
+}
+
+func validateDatasource(w http.ResponseWriter, o *org, id int64, ds map[string]interface{}) bool {
+	name, _ := ds["name"].(string)
+	if name == "" {
+		writeJSON(w, http.StatusUnprocessableEntity, []map[string]interface{}{
+			{"fieldNames": []string{"Name"}, "classification": "RequiredError", "message": "Required"},
+		})
+		return false
+	}
+	for otherID, other := range o.datasources {
+		if otherID != id && other["name"] == name {
+			writeError(w, http.StatusConflict, "data source with the same name already exists")
+			return false
+		}
+	}
+	return true
+}
+
+func createDatasource(s *Server, w http.ResponseWriter, r *request) {
+	var ds map[string]interface{}
+	if err := r.decode(&ds); err != nil {
+		writeError(w, http.StatusBadRequest, "bad request data")
+		return
+	}
+	if !validateDatasource(w, r.org, 0, ds) {
+		return
+	}
+	id := s.saveDatasource(r.org, 0, ds)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"datasource": ds, "id": id, "message": "Datasource added", "name": ds["name"],
+	})
+}
+
+func updateDatasource(s *Server, w http.ResponseWriter, r *request) {
+	id, existing := findDatasource(r)
+	if existing == nil {
+		writeError(w, http.StatusNotFound, "Data source not found")
+		return
+	}
+	var ds map[string]interface{}
+	if err := r.decode(&ds); err != nil {
+		writeError(w, http.StatusBadRequest, "bad request data")
+		return
+	}
+	if !validateDatasource(w, r.org, id, ds) {
+		return
+	}
+	if uid, _ := ds["uid"].(string); uid == "" {
+		ds["uid"] = existing["uid"]
+	}
+	s.saveDatasource(r.org, id, ds)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"datasource": ds, "id": id, "message": "Datasource updated", "name": ds["name"],
+	})
+}
+
+func deleteDatasource(s *Server, w http.ResponseWriter, r *request) {
+	id, ds := findDatasource(r)
+	if ds == nil {
+		writeError(w, http.StatusNotFound, "Data source not found")
+		return
+	}
+	delete(r.org.datasources, id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Data source deleted", "id": id})
+}
+
+func folderJSON(f *folder) map[string]interface{} {
+	return map[string]interface{}{
+		"id": f.id, "uid": f.uid, "title": f.title,
+		"url": "/dashboards/f/" + f.uid + "/" + slugify(f.title), "version": f.version,
+	}
+}
+
+func sortedFolders(o *org) []*folder {
+	list := make([]*folder, 0, len(o.folders))
+	for _, f := range o.folders {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].title) < strings.ToLower(list[j].title) })
+	return list
+}
+
+func listFolders(s *Server, w http.ResponseWriter, r *request) {
+	var list []interface{}
+	for _, f := range sortedFolders(r.org) {
+		list = append(list, map[string]interface{}{"id": f.id, "uid": f.uid, "title": f.title})
+	}
+	writeJSON(w, http.StatusOK, paginate(list, r, "limit", 1000))
+}
+
+func getFolder(s *Server, w http.ResponseWriter, r *request) {
+	if f, ok := r.org.folders[r.params["uid"]]; ok {
+		writeJSON(w, http.StatusOK, folderJSON(f))
+		return
+	}
+	writeError(w, http.StatusNotFound, "folder not found")
+}
+
+func createFolder(s *Server, w http.ResponseWriter, r *request) {
+	var body struct {
+		UID   string `json:"uid"`
+		Title string `json:"title"`
+	}
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "bad request data")
+		return
+	}
+	if body.Title == "" {
+		writeError(w, http.StatusBadRequest, "folder title cannot be empty")
+		return
+	}
+	if _, exists := r.org.folders[body.UID]; exists {
+		writeError(w, http.StatusConflict, "a folder with the same uid already exists")
+		return
+	}
+	for _, f := range r.org.folders {
+		if strings.EqualFold(f.title, body.Title) {
+			writeError(w, http.StatusConflict, "a folder or dashboard in the general folder with the same name already exists")
+			return
+		}
+	}
+	if body.UID == "" {
+		body.UID = s.newUID()
+	}
+	f := &folder{id: s.newID(), uid: body.UID, title: body.Title, version: 1}
+	r.org.folders[f.uid] = f
+	writeJSON(w, http.StatusOK, folderJSON(f))
+}
+
+func deleteFolder(s *Server, w http.ResponseWriter, r *request) {
+	f, ok := r.org.folders[r.params["uid"]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "folder not found")
+		return
+	}
+	for uid, d := range r.org.dashboards {
+		if d.folderUID == f.uid {
+			delete(r.org.dashboards, uid)
+		}
+	}
+	delete(r.org.folders, f.uid)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": f.id, "title": f.title, "message": "Folder deleted"})
+}
+
+// search implements /api/search with the type, query, tag, folderUIDs,
+// dashboardUIDs, limit and page parameters. Folders come before dashboards,
+// each sorted by title.
+func search(s *Server, w http.ResponseWriter, r *request) {
+	q := r.URL.Query()
+	typ := q.Get("type")
+	query := strings.ToLower(q.Get("query"))
+	tags := q["tag"]
+	folderUIDs := splitValues(q["folderUIDs"])
+	dashboardUIDs := splitValues(q["dashboardUIDs"])
+
+	var results []interface{}
+	if typ == "" || typ == "dash-folder" {
+		for _, f := range sortedFolders(r.org) {
+			if !strings.Contains(strings.ToLower(f.title), query) || len(tags) > 0 || len(dashboardUIDs) > 0 {
+				continue
+			}
+			results = append(results, map[string]interface{}{
+				"id": f.id, "uid": f.uid, "title": f.title, "type": "dash-folder",
+				"url": "/dashboards/f/" + f.uid + "/" + slugify(f.title), "tags": []string{},
+			})
+		}
+	}
+	if typ == "" || typ == "dash-db" {
+		for _, d := range sortedDashboards(r.org) {
+			data := d.current().data
+			title, _ := data["title"].(string)
+			dashTags := stringList(data["tags"])
+			switch {
+			case !strings.Contains(strings.ToLower(title), query),
+				!containsAll(dashTags, tags),
+				len(folderUIDs) > 0 && !contains(folderUIDs, d.folderUID),
+				len(dashboardUIDs) > 0 && !contains(dashboardUIDs, d.uid):
+				continue
+			}
+			item := map[string]interface{}{
+				"id": d.id, "uid": d.uid, "title": title, "type": "dash-db",
+				"uri": "db/" + slugify(title), "url": "/d/" + d.uid + "/" + slugify(title),
+				"slug": "", "tags": dashTags, "isStarred": false,
+			}
+			if f, ok := r.org.folders[d.folderUID]; ok {
+				item["folderId"] = f.id
+				item["folderUid"] = f.uid
+				item["folderTitle"] = f.title
+				item["folderUrl"] = "/dashboards/f/" + f.uid + "/" + slugify(f.title)
+			}
+			results = append(results, item)
+		}
+	}
+	writeJSON(w, http.StatusOK, paginate(results, r, "limit", 1000))
+}
+
+func sortedDashboards(o *org) []*dashboard {
+	list := make([]*dashboard, 0, len(o.dashboards))
+	for _, d := range o.dashboards {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		ti, _ := list[i].current().data["title"].(string)
+		tj, _ := list[j].current().data["title"].(string)
+		if !strings.EqualFold(ti, tj) {
+			return strings.ToLower(ti) < strings.ToLower(tj)
+		}
+		return list[i].uid < list[j].uid
+	})
+	return list
+}
+
+func splitValues(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := []string{}
+	for _, e := range list {
+		if s, ok := e.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(list, want []string) bool {
+	for _, w := range want {
+		if !contains(list, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func getDashboard(s *Server, w http.ResponseWriter, r *request) {
+	d, ok := r.org.dashboards[r.params["uid"]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Dashboard not found")
+		return
+	}
+	cur := d.current()
+	title, _ := cur.data["title"].(string)
+	meta := map[string]interface{}{
+		"type": "db", "slug": slugify(title), "url": "/d/" + d.uid + "/" + slugify(title),
+		"version": cur.version, "created": d.versions[0].created.Format(time.RFC3339),
+		"updated": cur.created.Format(time.RFC3339), "createdBy": d.versions[0].createdBy,
+		"updatedBy": cur.createdBy, "canSave": true, "canEdit": true,
+		"folderId": 0, "folderUid": "", "folderTitle": "General", "folderUrl": "",
+	}
+	if f, ok := r.org.folders[d.folderUID]; ok {
+		meta["folderId"] = f.id
+		meta["folderUid"] = f.uid
+		meta["folderTitle"] = f.title
+		meta["folderUrl"] = "/dashboards/f/" + f.uid + "/" + slugify(f.title)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"dashboard": copyMap(cur.data), "meta": meta})
+}
+
+// saveDashboard implements POST /api/dashboards/db, including Grafana's
+// version-mismatch and name-exists checks when overwrite is false.
+func saveDashboard(s *Server, w http.ResponseWriter, r *request) {
+	var body struct {
+		Dashboard map[string]interface{} `json:"dashboard"`
+		FolderUID string                 `json:"folderUid"`
+		Overwrite bool                   `json:"overwrite"`
+		Message   string                 `json:"message"`
+	}
+	if err := r.decode(&body); err != nil || body.Dashboard == nil {
+		writeError(w, http.StatusBadRequest, "bad request data")
+		return
+	}
+	model := body.Dashboard
+	title, _ := model["title"].(string)
+	if strings.TrimSpace(title) == "" {
+		writeStatusError(w, http.StatusBadRequest, "Dashboard title cannot be empty", "empty-name")
+		return
+	}
+	if body.FolderUID != "" {
+		if _, ok := r.org.folders[body.FolderUID]; !ok {
+			writeError(w, http.StatusBadRequest, "folder not found")
+			return
+		}
+	}
+
+	uid, _ := model["uid"].(string)
+	existing := r.org.dashboards[uid]
+	if !body.Overwrite {
+		if existing != nil {
+			version, _ := model["version"].(float64)
+			if int(version) != existing.current().version {
+				writeStatusError(w, http.StatusPreconditionFailed, "The dashboard has been changed by someone else", "version-mismatch")
+				return
+			}
+		}
+		for _, d := range r.org.dashboards {
+			other, _ := d.current().data["title"].(string)
+			if d.uid != uid && d.folderUID == body.FolderUID && strings.EqualFold(other, title) {
+				writeStatusError(w, http.StatusPreconditionFailed, "A dashboard with the same name in the folder already exists", "name-exists")
+				return
+			}
+		}
+	}
+
+	d := s.saveDashboard(r.org, body.FolderUID, model, body.Message, s.requestUser(r))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id": d.id, "uid": d.uid, "url": "/d/" + d.uid + "/" + slugify(title),
+		"status": "success", "version": d.current().version, "slug": slugify(title),
+	})
+}
+
+func (s *Server) requestUser(r *request) string {
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+	return "sa-grafanatest"
+}
+
+// saveDashboard stores model as the next version of its dashboard.
+func (s *Server) saveDashboard(o *org, folderUID string, model map[string]interface{}, message, user string) *dashboard {
+	uid, _ := model["uid"].(string)
+	if uid == "" {
+		uid = s.newUID()
+	}
+	d, ok := o.dashboards[uid]
+	if !ok {
+		d = &dashboard{id: s.newID(), uid: uid}
+		o.dashboards[uid] = d
+	}
+	d.folderUID = folderUID
+	version := len(d.versions) + 1
+	if len(d.versions) > 0 {
+		version = d.current().version + 1
+	}
+	model["id"] = d.id
+	model["uid"] = uid
+	model["version"] = version
+	d.versions = append(d.versions, dashboardVersion{
+		version:   version,
+		created:   time.Now().UTC(),
+		createdBy: user,
+		message:   message,
+		data:      model,
+	})
+	return d
+}
+
+func deleteDashboard(s *Server, w http.ResponseWriter, r *request) {
+	d, ok := r.org.dashboards[r.params["uid"]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Dashboard not found")
+		return
+	}
+	title, _ := d.current().data["title"].(string)
+	delete(r.org.dashboards, d.uid)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id": d.id, "title": title, "message": fmt.Sprintf("Dashboard %s deleted", title),
+	})
+}
+
+func versionJSON(d *dashboard, v *dashboardVersion) map[string]interface{} {
+	parent := v.version - 1
+	if parent < 1 {
+		parent = 1
+	}
+	return map[string]interface{}{
+		"id": d.id*1000 + int64(v.version), "dashboardId": d.id, "uid": d.uid,
+		"parentVersion": parent, "restoredFrom": v.restoredFrom, "version": v.version,
+		"created": v.created.Format(time.RFC3339), "createdBy": v.createdBy, "message": v.message,
+	}
+}
+
+// listDashboardVersions returns the versions of a dashboard, newest first.
+// Grafana 11 wraps the list in an object with a continueToken.
+func listDashboardVersions(s *Server, w http.ResponseWriter, r *request) {
+	d, ok := r.org.dashboards[r.params["uid"]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Dashboard not found")
+		return
+	}
+	list := make([]interface{}, 0, len(d.versions))
+	for i := len(d.versions) - 1; i >= 0; i-- {
+		list = append(list, versionJSON(d, &d.versions[i]))
+	}
+	list = paginate(list, r, "limit", 1000)
+	if s.majorVersion() >= 11 {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"continueToken": "", "versions": list})
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) majorVersion() int {
+	major, _ := strconv.Atoi(strings.SplitN(s.Version, ".", 2)[0])
+	return major
+}
+
+func findVersion(d *dashboard, version string) *dashboardVersion {
+	for i := range d.versions {
+		if strconv.Itoa(d.versions[i].version) == version {
+			return &d.versions[i]
+		}
+	}
+	return nil
+}
+
+func getDashboardVersion(s *Server, w http.ResponseWriter, r *request) {
+	d, ok := r.org.dashboards[r.params["uid"]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Dashboard not found")
+		return
+	}
+	v := findVersion(d, r.params["version"])
+	if v == nil {
+		writeError(w, http.StatusNotFound, "Dashboard version not found")
+		return
+	}
+	body := versionJSON(d, v)
+	body["data"] = copyMap(v.data)
+	writeJSON(w, http.StatusOK, body)
+}
+
+// restoreDashboard saves an old version as the newest one, as Grafana does.
+func restoreDashboard(s *Server, w http.ResponseWriter, r *request) {
+	d, ok := r.org.dashboards[r.params["uid"]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Dashboard not found")
+		return
+	}
+	var body struct {
+		Version int `json:"version"`
+	}
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "bad request data")
+		return
+	}
+	v := findVersion(d, strconv.Itoa(body.Version))
+	if v == nil {
+		writeError(w, http.StatusNotFound, "Dashboard version not found")
+		return
+	}
+	s.saveDashboard(r.org, d.folderUID, copyMap(v.data), fmt.Sprintf("Restored from version %d", v.version), s.requestUser(r))
+	cur := d.current()
+	cur.restoredFrom = v.version
+	title, _ := cur.data["title"].(string)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id": d.id, "uid": d.uid, "slug": slugify(title), "url": "/d/" + d.uid + "/" + slugify(title),
+		"status": "success", "version": cur.version,
+	})
+}
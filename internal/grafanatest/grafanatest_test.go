@@ -0,0 +1,159 @@
+package grafanatest
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gcli/internal/client"
+	"gcli/internal/config"
+)
+
+func newClient(t *testing.T, p config.Profile, orgID string) *client.Client {
+	t.Helper()
+	c, err := client.New(&p, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := newClient(t, srv.Profile("test"), "")
+
+	// Authentication.
+	bad := srv.Profile("bad")
+	bad.Token = "wrong"
+	if err := newClient(t, bad, "").Get("/api/org", nil); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("expected unauthorized with a wrong token, got %v", err)
+	}
+	basic := config.Profile{URL: srv.URL, User: DefaultUser, Pass: DefaultPassword}
+	if err := newClient(t, basic, "").Get("/api/org", nil); err != nil {
+		t.Errorf("expected basic auth to work, got %v", err)
+	}
+
+	// Organizations and org scoping.
+	var created struct {
+		OrgID int64 `json:"orgId"`
+	}
+	if err := c.Post("/api/orgs", map[string]string{"name": "Staging"}, &created); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Post("/api/orgs", map[string]string{"name": "Staging"}, nil); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("expected conflict for a duplicate org, got %v", err)
+	}
+	srv.AddDatasource(MainOrgID, Datasource{Name: "Prometheus", Type: "prometheus"})
+	staging := newClient(t, srv.Profile("test"), strconv.FormatInt(created.OrgID, 10))
+	var list []map[string]interface{}
+	if err := staging.Get("/api/datasources", &list); err != nil || len(list) != 0 {
+		t.Errorf("expected no datasources in the new org, got %v (%v)", list, err)
+	}
+	if err := newClient(t, srv.Profile("test"), "99").Get("/api/datasources", nil); !errors.Is(err, client.ErrForbidden) {
+		t.Errorf("expected forbidden for an unknown org, got %v", err)
+	}
+
+	// Datasources.
+	if err := c.Post("/api/datasources", map[string]string{"type": "loki"}, nil); !errors.Is(err, client.ErrValidation) || !strings.Contains(err.Error(), "Name: Required") {
+		t.Errorf("expected a validation error without a name, got %v", err)
+	}
+	var ds map[string]interface{}
+	if err := c.Get("/api/datasources/name/Prometheus", &ds); err != nil || ds["uid"] == "" {
+		t.Errorf("expected the datasource by name with a generated uid, got %v (%v)", ds, err)
+	}
+	if err := c.Delete("/api/datasources/name/Prometheus", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get("/api/datasources/name/Prometheus", nil); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("expected not found after delete, got %v", err)
+	}
+
+	// Dashboards, folders and versions.
+	srv.AddFolder(MainOrgID, "ops", "Operations")
+	uid := srv.AddDashboard(MainOrgID, "ops", map[string]interface{}{"uid": "cpu", "title": "CPU"})
+	save := map[string]interface{}{
+		"dashboard": map[string]interface{}{"uid": uid, "title": "CPU", "version": 1},
+		"folderUid": "ops",
+	}
+	var saved struct {
+		Version int `json:"version"`
+	}
+	if err := c.Post("/api/dashboards/db", save, &saved); err != nil || saved.Version != 2 {
+		t.Fatalf("expected version 2 after saving, got %d (%v)", saved.Version, err)
+	}
+	if err := c.Post("/api/dashboards/db", save, nil); !errors.Is(err, client.ErrConflict) || !strings.Contains(err.Error(), "changed by someone else") {
+		t.Errorf("expected a version mismatch for a stale version, got %v", err)
+	}
+	save["overwrite"] = true
+	if err := c.Post("/api/dashboards/db", save, nil); err != nil {
+		t.Errorf("expected overwrite to ignore the version, got %v", err)
+	}
+	var search []struct {
+		UID         string `json:"uid"`
+		FolderTitle string `json:"folderTitle"`
+	}
+	if err := c.Get("/api/search?type=dash-db&query=cp", &search); err != nil || len(search) != 1 || search[0].FolderTitle != "Operations" {
+		t.Errorf("expected the dashboard in Operations, got %+v (%v)", search, err)
+	}
+
+	// Pagination.
+	for i := 0; i < 3; i++ {
+		srv.AddOrg("Org " + strconv.Itoa(i))
+	}
+	var page []map[string]interface{}
+	if err := c.Get("/api/orgs?perpage=2&page=3", &page); err != nil || len(page) != 1 {
+		t.Errorf("expected one org on the last page, got %v (%v)", page, err)
+	}
+
+	if err := c.Get("/api/nope", nil); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("expected not found for an unknown path, got %v", err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddDatasource(MainOrgID, Datasource{Name: "Prometheus", Type: "prometheus"})
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+
+	rec := NewRecorder(nil)
+	client.SetTransport(func(next http.RoundTripper) http.RoundTripper {
+		rec.Next = next
+		return rec
+	})
+	c := newClient(t, srv.Profile("test"), "")
+	var before []map[string]interface{}
+	c.Get("/api/datasources", &before)
+	c.Post("/api/datasources", map[string]string{"name": "Loki", "type": "loki"}, nil)
+	c.Get("/api/datasources/name/Missing", nil)
+	if err := rec.Save(fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := LoadReplayer(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetTransport(func(http.RoundTripper) http.RoundTripper { return replayer })
+	defer client.SetTransport(nil)
+	srv.Close()
+
+	c = newClient(t, srv.Profile("test"), "")
+	var after []map[string]interface{}
+	if err := c.Get("/api/datasources", &after); err != nil || len(after) != 1 || after[0]["name"] != "Prometheus" {
+		t.Errorf("expected the recorded datasource list, got %v (%v)", after, err)
+	}
+	if err := c.Post("/api/datasources", map[string]interface{}{"type": "loki", "name": "Loki"}, nil); err != nil {
+		t.Errorf("expected the recorded create, got %v", err)
+	}
+	if err := c.Post("/api/datasources", map[string]string{"name": "Tempo"}, nil); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("expected an error for an unrecorded request, got %v", err)
+	}
+	if unused := replayer.Unused(); len(unused) != 1 || unused[0].Status != http.StatusNotFound {
+		t.Errorf("expected the 404 to be unused, got %+v", unused)
+	}
+}
@@ -0,0 +1,220 @@
+package grafanatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Interaction is one recorded request and its response. JSON bodies are kept
+// as JSON so fixture files stay readable and easy to edit; other bodies are
+// kept as text.
+type Interaction struct {
+	Method       string          `json:"method"`
+	Path         string          `json:"path"`
+	OrgID        string          `json:"orgId,omitempty"`
+	Request      json.RawMessage `json:"request,omitempty"`
+	RequestText  string          `json:"requestText,omitempty"`
+	Status       int             `json:"status"`
+	Response     json.RawMessage `json:"response,omitempty"`
+	ResponseText string          `json:"responseText,omitempty"`
+}
+
+// LoadInteractions reads a fixture file written by a Recorder.
+func LoadInteractions(path string) ([]Interaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []Interaction
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// SaveInteractions writes interactions to a fixture file.
+func SaveInteractions(path string, list []Interaction) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Recorder is an http.RoundTripper that passes requests to Next and records
+// each exchange. Authentication headers are never recorded.
+type Recorder struct {
+	Next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder returns a Recorder sending requests through next, or
+// http.DefaultTransport when next is nil.
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{Next: next}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	in := Interaction{
+		Method: req.Method,
+		Path:   req.URL.RequestURI(),
+		OrgID:  req.Header.Get("X-Grafana-Org-Id"),
+		Status: resp.StatusCode,
+	}
+	in.Request, in.RequestText = splitBody(reqBody)
+	in.Response, in.ResponseText = splitBody(respBody)
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, in)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Interactions returns the exchanges recorded so far.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// Save writes the recorded exchanges to a fixture file.
+func (r *Recorder) Save(path string) error {
+	return SaveInteractions(path, r.Interactions())
+}
+
+// Replayer is an http.RoundTripper that answers requests from recorded
+// interactions instead of sending them. A request matches the first unused
+// interaction with the same method, path and query, organization and body;
+// JSON bodies are compared by value. Unmatched requests fail.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer returns a Replayer for interactions.
+func NewReplayer(interactions []Interaction) *Replayer {
+	return &Replayer{interactions: interactions, used: make([]bool, len(interactions))}
+}
+
+// LoadReplayer returns a Replayer for a fixture file.
+func LoadReplayer(path string) (*Replayer, error) {
+	list, err := LoadInteractions(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(list), nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.interactions {
+		if r.used[i] || !in.matches(req, body) {
+			continue
+		}
+		r.used[i] = true
+		respBody := []byte(in.ResponseText)
+		contentType := "text/plain; charset=utf-8"
+		if len(in.Response) > 0 {
+			respBody = in.Response
+			contentType = "application/json"
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+			StatusCode:    in.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {contentType}},
+			Body:          io.NopCloser(bytes.NewReader(respBody)),
+			ContentLength: int64(len(respBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("grafanatest: no recorded response for %s %s", req.Method, req.URL.RequestURI())
+}
+
+// Unused returns the interactions that no request has matched yet.
+func (r *Replayer) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []Interaction
+	for i, in := range r.interactions {
+		if !r.used[i] {
+			list = append(list, in)
+		}
+	}
+	return list
+}
+
+func (in Interaction) matches(req *http.Request, body []byte) bool {
+	if in.Method != req.Method || in.Path != req.URL.RequestURI() || in.OrgID != req.Header.Get("X-Grafana-Org-Id") {
+		return false
+	}
+	if len(in.Request) == 0 {
+		return in.RequestText == string(body)
+	}
+	var want, got interface{}
+	if json.Unmarshal(in.Request, &want) != nil || json.Unmarshal(body, &got) != nil {
+		return false
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+// splitBody returns body as JSON when it is valid JSON, otherwise as text.
+func splitBody(body []byte) (json.RawMessage, string) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, ""
+	}
+	if json.Valid(trimmed) {
+		var compact bytes.Buffer
+		json.Compact(&compact, trimmed)
+		return compact.Bytes(), ""
+	}
+	return nil, strings.TrimRight(string(body), "\n")
+}
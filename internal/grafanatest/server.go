@@ -0,0 +1,393 @@
+// Package grafanatest provides an in-memory fake of the Grafana HTTP API and
+// a record/replay transport, for testing gcli commands and other code built
+// on internal/client without a live Grafana.
+package grafanatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gcli/internal/config"
+)
+
+// Default credentials accepted by a new Server.
+const (
+	DefaultToken    = "glsa_grafanatest"
+	DefaultUser     = "admin"
+	DefaultPassword = "admin"
+)
+
+// MainOrgID is the ID of the organization every Server starts with.
+const MainOrgID = 1
+
+// Server is a fake Grafana instance. It keeps organizations, datasources,
+// folders and dashboards in memory, scopes them by the X-Grafana-Org-Id
+// header and answers with the status codes and error bodies of Grafana.
+//
+// Its fields may be changed before the first request.
+type Server struct {
+	*httptest.Server
+
+	// Token is the accepted Bearer token; User and Password the accepted
+	// basic auth credentials.
+	Token    string
+	User     string
+	Password string
+	// Version is reported by /api/health. From 11 on, dashboard versions are
+	// listed in Grafana 11's paged format.
+	Version string
+
+	mu       sync.Mutex
+	orgs     map[int64]*org
+	nextID   int64
+	requests []string
+}
+
+type org struct {
+	id          int64
+	name        string
+	datasources map[int64]map[string]interface{}
+	folders     map[string]*folder
+	dashboards  map[string]*dashboard
+}
+
+type folder struct {
+	id      int64
+	uid     string
+	title   string
+	version int
+}
+
+type dashboard struct {
+	id        int64
+	uid       string
+	folderUID string
+	versions  []dashboardVersion
+}
+
+type dashboardVersion struct {
+	version      int
+	restoredFrom int
+	created      time.Time
+	createdBy    string
+	message      string
+	data         map[string]interface{}
+}
+
+func (d *dashboard) current() *dashboardVersion {
+	return &d.versions[len(d.versions)-1]
+}
+
+// NewServer starts a fake Grafana with an empty "Main Org.". Call Close when
+// done.
+func NewServer() *Server {
+	s := &Server{
+		Token:    DefaultToken,
+		User:     DefaultUser,
+		Password: DefaultPassword,
+		Version:  "10.4.0",
+		orgs:     map[int64]*org{},
+		nextID:   1,
+	}
+	s.addOrg("Main Org.")
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Profile returns a gcli profile pointing at the server with token auth.
+func (s *Server) Profile(name string) config.Profile {
+	return config.Profile{Name: name, URL: s.URL, Token: s.Token}
+}
+
+// Requests returns the requests served so far as "METHOD /path?query".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// AddOrg creates an organization and returns its ID.
+func (s *Server) AddOrg(name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addOrg(name).id
+}
+
+// Datasource describes a datasource seeded with AddDatasource.
+type Datasource struct {
+	UID       string
+	Name      string
+	Type      string
+	URL       string
+	Access    string
+	IsDefault bool
+}
+
+// AddDatasource creates a datasource in an organization and returns its ID.
+func (s *Server) AddDatasource(orgID int64, ds Datasource) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.mustOrg(orgID)
+	if ds.Access == "" {
+		ds.Access = "proxy"
+	}
+	return s.saveDatasource(o, 0, map[string]interface{}{
+		"uid":       ds.UID,
+		"name":      ds.Name,
+		"type":      ds.Type,
+		"url":       ds.URL,
+		"access":    ds.Access,
+		"isDefault": ds.IsDefault,
+	})
+}
+
+// Datasources returns the datasources of an organization, sorted by name.
+func (s *Server) Datasources(orgID int64) []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedDatasources(s.mustOrg(orgID))
+}
+
+// AddFolder creates a folder in an organization.
+func (s *Server) AddFolder(orgID int64, uid, title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.mustOrg(orgID)
+	o.folders[uid] = &folder{id: s.newID(), uid: uid, title: title, version: 1}
+}
+
+// AddDashboard stores a dashboard model in a folder ("" for General) and
+// returns its UID, generated when the model has none.
+func (s *Server) AddDashboard(orgID int64, folderUID string, model map[string]interface{}) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveDashboard(s.mustOrg(orgID), folderUID, copyMap(model), "", "admin").uid
+}
+
+// Dashboard returns the current model of a dashboard.
+func (s *Server) Dashboard(orgID int64, uid string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.mustOrg(orgID).dashboards[uid]
+	if !ok {
+		return nil, false
+	}
+	return copyMap(d.current().data), true
+}
+
+// DashboardFolder returns the folder UID of a dashboard, "" for General.
+func (s *Server) DashboardFolder(orgID int64, uid string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.mustOrg(orgID).dashboards[uid]
+	if !ok {
+		return "", false
+	}
+	return d.folderUID, true
+}
+
+func (s *Server) addOrg(name string) *org {
+	o := &org{
+		id:          s.newID(),
+		name:        name,
+		datasources: map[int64]map[string]interface{}{},
+		folders:     map[string]*folder{},
+		dashboards:  map[string]*dashboard{},
+	}
+	s.orgs[o.id] = o
+	return o
+}
+
+func (s *Server) mustOrg(id int64) *org {
+	o, ok := s.orgs[id]
+	if !ok {
+		panic(fmt.Sprintf("grafanatest: organization %d does not exist", id))
+	}
+	return o
+}
+
+func (s *Server) newID() int64 {
+	id := s.nextID
+	s.nextID++
+	return id
+}
+
+// request is the context passed to route handlers.
+type request struct {
+	*http.Request
+	org    *org
+	params map[string]string
+}
+
+func (r *request) decode(v interface{}) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+type route struct {
+	method  string
+	pattern *regexp.Regexp
+	names   []string
+	handle  func(s *Server, w http.ResponseWriter, r *request)
+}
+
+var routes []route
+
+// handle registers a handler for a path pattern with :name parameters.
+func handle(method, pattern string, h func(s *Server, w http.ResponseWriter, r *request)) {
+	var names []string
+	re := regexp.MustCompile(`:[a-zA-Z]+`).ReplaceAllStringFunc(pattern, func(p string) string {
+		names = append(names, p[1:])
+		return `([^/]+)`
+	})
+	routes = append(routes, route{method: method, pattern: regexp.MustCompile("^" + re + "$"), names: names, handle: h})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+	w.Header().Set("Content-Type", "application/json")
+
+	if !s.authorized(w, r) {
+		return
+	}
+	o, ok := s.requestOrg(w, r)
+	if !ok {
+		return
+	}
+
+	pathFound := false
+	for _, rt := range routes {
+		m := rt.pattern.FindStringSubmatch(r.URL.Path)
+		if m == nil {
+			continue
+		}
+		pathFound = true
+		if rt.method != r.Method {
+			continue
+		}
+		params := map[string]string{}
+		for i, name := range rt.names {
+			params[name] = m[i+1]
+		}
+		rt.handle(s, w, &request{Request: r, org: o, params: params})
+		return
+	}
+	if pathFound {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	writeError(w, http.StatusNotFound, "Not found")
+}
+
+func (s *Server) authorized(w http.ResponseWriter, r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(auth, "Bearer "):
+		if strings.TrimPrefix(auth, "Bearer ") == s.Token {
+			return true
+		}
+		writeError(w, http.StatusUnauthorized, "Invalid API key")
+	case auth != "":
+		user, pass, ok := r.BasicAuth()
+		if ok && user == s.User && pass == s.Password {
+			return true
+		}
+		writeError(w, http.StatusUnauthorized, "invalid username or password")
+	default:
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+	}
+	return false
+}
+
+func (s *Server) requestOrg(w http.ResponseWriter, r *http.Request) (*org, bool) {
+	header := r.Header.Get("X-Grafana-Org-Id")
+	if header == "" {
+		return s.orgs[MainOrgID], true
+	}
+	id, err := strconv.ParseInt(header, 10, 64)
+	if o, ok := s.orgs[id]; err == nil && ok {
+		return o, true
+	}
+	writeError(w, http.StatusForbidden, "You are not a member of organization "+header)
+	return nil, false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+func writeStatusError(w http.ResponseWriter, status int, message, code string) {
+	writeJSON(w, status, map[string]string{"message": message, "status": code})
+}
+
+// paginate applies the page parameter and a page size read from sizeParam.
+func paginate(items []interface{}, r *request, sizeParam string, defaultSize int) []interface{} {
+	size, err := strconv.Atoi(r.URL.Query().Get(sizeParam))
+	if err != nil || size <= 0 {
+		size = defaultSize
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	start := (page - 1) * size
+	if start >= len(items) {
+		return []interface{}{}
+	}
+	end := start + size
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func (s *Server) newUID() string {
+	return fmt.Sprintf("gen%06d", s.newID())
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	b, _ := json.Marshal(m)
+	var out map[string]interface{}
+	json.Unmarshal(b, &out)
+	return out
+}
+
+func sortedDatasources(o *org) []map[string]interface{} {
+	list := make([]map[string]interface{}, 0, len(o.datasources))
+	for _, ds := range o.datasources {
+		list = append(list, copyMap(ds))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(fmt.Sprint(list[i]["name"])) < strings.ToLower(fmt.Sprint(list[j]["name"]))
+	})
+	return list
+}
@@ -0,0 +1,245 @@
+// Package keyring stores profile secrets in the operating system's secret
+// service instead of the gcli config file.
+//
+// On Linux the freedesktop Secret Service (GNOME Keyring, KWallet, ...) is
+// reached through libsecret's secret-tool, on macOS through the login
+// keychain's security tool.
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// service is the name secrets are filed under in the secret service.
+const service = "gcli"
+
+// ErrNotFound is returned when no secret is stored for an account.
+var ErrNotFound = errors.New("secret not found in keyring")
+
+// Backend stores secrets keyed by account, which is the profile name.
+type Backend interface {
+	Get(account string) (string, error)
+	Set(account, secret string) error
+	Delete(account string) error
+}
+
+// Default is the backend used by gcli. Tests may replace it with NewMemory().
+var Default Backend = detect()
+
+func detect() Backend {
+	switch runtime.GOOS {
+	case "darwin":
+		return macOS{}
+	case "windows":
+		return unsupported{}
+	default:
+		return secretService{}
+	}
+}
+
+// command is an invocation of a secret service tool. The secret is only ever
+// passed on stdin, never as an argument where other users could see it in
+// the process list.
+type command struct {
+	name  string
+	args  []string
+	stdin string
+	// script marks tools reading commands from stdin. They exit 0 even when
+	// a command fails, so anything on stderr counts as a failure.
+	script bool
+}
+
+// secretService talks to the freedesktop Secret Service over D-Bus using the
+// secret-tool command from libsecret.
+type secretService struct{}
+
+func (secretService) getCommand(account string) command {
+	return command{name: "secret-tool", args: []string{"lookup", "service", service, "account", account}}
+}
+
+func (secretService) setCommand(account, secret string) command {
+	label := fmt.Sprintf("gcli profile %s", account)
+	return command{
+		name:  "secret-tool",
+		args:  []string{"store", "--label", label, "service", service, "account", account},
+		stdin: secret,
+	}
+}
+
+func (secretService) deleteCommand(account string) command {
+	return command{name: "secret-tool", args: []string{"clear", "service", service, "account", account}}
+}
+
+func (s secretService) Get(account string) (string, error) {
+	out, err := s.getCommand(account).run()
+	// secret-tool exits with 1 and prints nothing when no secret is stored;
+	// other failures come with a message.
+	if out == "" && (err == nil || exitedQuietly(err, 1)) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+func (s secretService) Set(account, secret string) error {
+	_, err := s.setCommand(account, secret).run()
+	return err
+}
+
+func (s secretService) Delete(account string) error {
+	_, err := s.deleteCommand(account).run()
+	return err
+}
+
+// macOS uses the login keychain through the security command.
+type macOS struct{}
+
+func (macOS) getCommand(account string) command {
+	return command{name: "security", args: []string{"find-generic-password", "-s", service, "-a", account, "-w"}}
+}
+
+// setCommand runs security interactively so the password is read from stdin;
+// add-generic-password only takes it as an argument.
+func (macOS) setCommand(account, secret string) command {
+	line := strings.Join([]string{
+		"add-generic-password", "-U",
+		"-s", securityQuote(service),
+		"-a", securityQuote(account),
+		"-w", securityQuote(secret),
+	}, " ")
+	return command{name: "security", args: []string{"-i"}, stdin: line + "\n", script: true}
+}
+
+func (macOS) deleteCommand(account string) command {
+	return command{name: "security", args: []string{"delete-generic-password", "-s", service, "-a", account}}
+}
+
+func (m macOS) Get(account string) (string, error) {
+	out, err := m.getCommand(account).run()
+	if err != nil {
+		// security exits with 44 when the item does not exist.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return out, nil
+}
+
+func (m macOS) Set(account, secret string) error {
+	_, err := m.setCommand(account, secret).run()
+	return err
+}
+
+func (m macOS) Delete(account string) error {
+	_, err := m.deleteCommand(account).run()
+	return err
+}
+
+// securityQuote quotes an argument for a line of security's interactive mode,
+// which splits on whitespace outside double quotes and unescapes backslashes.
+func securityQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+type unsupported struct{}
+
+func (unsupported) Get(string) (string, error) { return "", errUnsupported() }
+func (unsupported) Set(string, string) error   { return errUnsupported() }
+func (unsupported) Delete(string) error        { return errUnsupported() }
+
+func errUnsupported() error {
+	return fmt.Errorf("keyring is not supported on %s; use credential_command instead", runtime.GOOS)
+}
+
+// commandError is a failed run of a secret service tool.
+type commandError struct {
+	name string
+	err  error
+	// stderr is what the tool printed on stderr, trimmed.
+	stderr string
+}
+
+func (e *commandError) Error() string {
+	if e.stderr != "" {
+		return fmt.Sprintf("%s: %v: %s", e.name, e.err, e.stderr)
+	}
+	return fmt.Sprintf("%s: %v", e.name, e.err)
+}
+
+func (e *commandError) Unwrap() error { return e.err }
+
+// exitedQuietly reports whether err is a tool exiting with code without a
+// message on stderr.
+func exitedQuietly(err error, code int) bool {
+	var cmdErr *commandError
+	var exitErr *exec.ExitError
+	return errors.As(err, &cmdErr) && cmdErr.stderr == "" &&
+		errors.As(err, &exitErr) && exitErr.ExitCode() == code
+}
+
+// run runs the tool and returns its output. The output is returned on
+// failure too, as some tools report a missing secret by their exit status.
+func (c command) run() (string, error) {
+	cmd := exec.Command(c.name, c.args...)
+	if c.stdin != "" {
+		cmd.Stdin = strings.NewReader(c.stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	raw, err := cmd.Output()
+	out := strings.TrimRight(string(raw), "\r\n")
+	msg := strings.TrimSpace(stderr.String())
+	if err == nil && c.script && msg != "" {
+		err = errors.New("command failed")
+	}
+	if err != nil {
+		return out, &commandError{name: c.name, err: err, stderr: msg}
+	}
+	return out, nil
+}
+
+// Memory is an in-process backend, useful in tests.
+type Memory struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// NewMemory returns an empty in-memory backend.
+func NewMemory() *Memory {
+	return &Memory{secrets: map[string]string{}}
+}
+
+func (m *Memory) Get(account string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.secrets[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *Memory) Set(account, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[account] = secret
+	return nil
+}
+
+func (m *Memory) Delete(account string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.secrets, account)
+	return nil
+}
@@ -0,0 +1,112 @@
+package keyring
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	const secret = `s3cr"et \ pass`
+
+	tests := []struct {
+		name string
+		got  command
+		want command
+	}{
+		{
+			"secret-tool lookup",
+			secretService{}.getCommand("prod"),
+			command{name: "secret-tool", args: []string{"lookup", "service", "gcli", "account", "prod"}},
+		},
+		{
+			"secret-tool store",
+			secretService{}.setCommand("prod", secret),
+			command{name: "secret-tool", args: []string{"store", "--label", "gcli profile prod", "service", "gcli", "account", "prod"}, stdin: secret},
+		},
+		{
+			"secret-tool clear",
+			secretService{}.deleteCommand("prod"),
+			command{name: "secret-tool", args: []string{"clear", "service", "gcli", "account", "prod"}},
+		},
+		{
+			"security find",
+			macOS{}.getCommand("prod"),
+			command{name: "security", args: []string{"find-generic-password", "-s", "gcli", "-a", "prod", "-w"}},
+		},
+		{
+			"security add",
+			macOS{}.setCommand("my prod", secret),
+			command{
+				name:   "security",
+				args:   []string{"-i"},
+				stdin:  `add-generic-password -U -s "gcli" -a "my prod" -w "s3cr\"et \\ pass"` + "\n",
+				script: true,
+			},
+		},
+		{
+			"security delete",
+			macOS{}.deleteCommand("prod"),
+			command{name: "security", args: []string{"delete-generic-password", "-s", "gcli", "-a", "prod"}},
+		},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, tt.got)
+		}
+		for _, arg := range tt.got.args {
+			if strings.Contains(arg, "s3cr") {
+				t.Errorf("%s: secret passed as argument %q", tt.name, arg)
+			}
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	if _, err := m.Get("prod"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	m.Set("prod", "secret")
+	if s, err := m.Get("prod"); err != nil || s != "secret" {
+		t.Errorf("expected the stored secret, got %q (%v)", s, err)
+	}
+	m.Delete("prod")
+	if _, err := m.Get("prod"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after Delete, got %v", err)
+	}
+}
+
+func TestSecretServiceGet(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as secret-tool")
+	}
+	// A fake secret-tool whose behaviour depends on the account looked up.
+	dir := t.TempDir()
+	script := `#!/bin/sh
+case "$5" in
+stored) echo "s3cret" ;;
+missing) exit 1 ;;
+locked) echo "secret-tool: Cannot create an item in a locked collection" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "secret-tool"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if s, err := (secretService{}).Get("stored"); err != nil || s != "s3cret" {
+		t.Errorf("expected the stored secret, got %q (%v)", s, err)
+	}
+	if _, err := (secretService{}).Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound when secret-tool exits 1 quietly, got %v", err)
+	}
+	_, err := (secretService{}).Get("locked")
+	if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "locked collection") {
+		t.Errorf("expected the secret-tool error, got %v", err)
+	}
+}
@@ -0,0 +1,604 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// This file implements the kubectl flavour of JSONPath used by
+// -o jsonpath=TEMPLATE. Supported: text with {EXPR} blocks, $ and @, .field,
+// ['field'], .*, [*], [n], [start:end], ..field, [?(@.path OP value)],
+// {range EXPR}...{end} and quoted literals such as {"\n"}.
+//
+// As in kubectl, a top-level list is also reachable as .items, so
+// '{.items[*].uid}' works on plain Grafana list responses.
+
+type jpNode struct {
+	text     string    // literal text, when expr is nil and not a range
+	expr     []jpStep  // expression to print
+	rangeOf  []jpStep  // set for {range EXPR}
+	children []*jpNode // body of a range
+}
+
+type jpStepKind int
+
+const (
+	jpField jpStepKind = iota
+	jpWildcard
+	jpIndex
+	jpSlice
+	jpRecursive
+	jpFilter
+	jpRoot
+)
+
+type jpStep struct {
+	kind       jpStepKind
+	name       string
+	index      int
+	start, end *int
+	filter     *jpCondition
+}
+
+type jpCondition struct {
+	left  []jpStep
+	op    string
+	right interface{}
+}
+
+// JSONPath is a parsed JSONPath template.
+type JSONPath struct {
+	nodes []*jpNode
+}
+
+// ParseJSONPath parses a template such as '{.items[*].title}'. A template
+// without braces is treated as a single expression.
+func ParseJSONPath(tmpl string) (*JSONPath, error) {
+	if !strings.Contains(tmpl, "{") {
+		tmpl = "{" + tmpl + "}"
+	}
+	var blocks []string // alternating text and {expr} contents, tagged below
+	var isExpr []bool
+	for len(tmpl) > 0 {
+		open := strings.IndexByte(tmpl, '{')
+		if open < 0 {
+			blocks, isExpr = append(blocks, tmpl), append(isExpr, false)
+			break
+		}
+		if open > 0 {
+			blocks, isExpr = append(blocks, tmpl[:open]), append(isExpr, false)
+		}
+		closeIdx := matchingBrace(tmpl, open)
+		if closeIdx < 0 {
+			return nil, fmt.Errorf("jsonpath: unclosed '{' in %q", tmpl)
+		}
+		blocks, isExpr = append(blocks, strings.TrimSpace(tmpl[open+1:closeIdx])), append(isExpr, true)
+		tmpl = tmpl[closeIdx+1:]
+	}
+
+	root := &jpNode{}
+	stack := []*jpNode{root}
+	for i, b := range blocks {
+		cur := stack[len(stack)-1]
+		if !isExpr[i] {
+			cur.children = append(cur.children, &jpNode{text: b})
+			continue
+		}
+		switch {
+		case b == "end":
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("jsonpath: {end} without {range}")
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasPrefix(b, "range ") || strings.HasPrefix(b, "range\t"):
+			steps, err := parseJSONPathExpr(strings.TrimSpace(b[len("range"):]))
+			if err != nil {
+				return nil, err
+			}
+			n := &jpNode{rangeOf: steps}
+			cur.children = append(cur.children, n)
+			stack = append(stack, n)
+		case strings.HasPrefix(b, `"`) || strings.HasPrefix(b, "'"):
+			lit, err := unquote(b)
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath: invalid literal %s", b)
+			}
+			cur.children = append(cur.children, &jpNode{text: lit})
+		default:
+			steps, err := parseJSONPathExpr(b)
+			if err != nil {
+				return nil, err
+			}
+			cur.children = append(cur.children, &jpNode{expr: steps})
+		}
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("jsonpath: {range} without {end}")
+	}
+	return &JSONPath{nodes: root.children}, nil
+}
+
+// Execute writes the template applied to data, which must consist of plain
+// maps, slices and scalars.
+func (jp *JSONPath) Execute(w io.Writer, data interface{}) error {
+	return executeJSONPath(w, jp.nodes, data, data)
+}
+
+func executeJSONPath(w io.Writer, nodes []*jpNode, root, current interface{}) error {
+	for _, n := range nodes {
+		switch {
+		case n.rangeOf != nil:
+			items, err := evalJSONPath(n.rangeOf, root, current)
+			if err != nil {
+				return err
+			}
+			for _, item := range flattenOnce(items) {
+				if err := executeJSONPath(w, n.children, root, item); err != nil {
+					return err
+				}
+			}
+		case n.expr != nil:
+			values, err := evalJSONPath(n.expr, root, current)
+			if err != nil {
+				return err
+			}
+			parts := make([]string, len(values))
+			for i, v := range values {
+				parts[i] = formatJSONPathValue(v)
+			}
+			if _, err := io.WriteString(w, strings.Join(parts, " ")); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.WriteString(w, n.text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flattenOnce makes {range .items} and {range .items[*]} equivalent.
+func flattenOnce(values []interface{}) []interface{} {
+	if len(values) == 1 {
+		if list, ok := values[0].([]interface{}); ok {
+			return list
+		}
+	}
+	return values
+}
+
+func formatJSONPathValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(val)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func matchingBrace(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func unquote(s string) (string, error) {
+	if strings.HasPrefix(s, "'") {
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : len(s)-1], nil
+	}
+	return strconv.Unquote(s)
+}
+
+// parseJSONPathExpr parses an expression such as .items[*].title.
+func parseJSONPathExpr(expr string) ([]jpStep, error) {
+	var steps []jpStep
+	s := strings.TrimSpace(expr)
+	switch {
+	case strings.HasPrefix(s, "$"):
+		steps = append(steps, jpStep{kind: jpRoot})
+		s = s[1:]
+	case strings.HasPrefix(s, "@"):
+		s = s[1:]
+	case s != "" && s[0] != '.' && s[0] != '[':
+		s = "." + s
+	}
+
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			steps = append(steps, jpStep{kind: jpRecursive})
+			s = s[2:]
+			if strings.HasPrefix(s, "[") {
+				continue
+			}
+			name, rest := readName(s)
+			if name == "" {
+				return nil, fmt.Errorf("jsonpath: expected field after '..' in %q", expr)
+			}
+			steps = append(steps, fieldStep(name))
+			s = rest
+		case s[0] == '.':
+			name, rest := readName(s[1:])
+			if name == "" {
+				// A lone "." refers to the current object.
+				if rest == "" {
+					return steps, nil
+				}
+				return nil, fmt.Errorf("jsonpath: expected field after '.' in %q", expr)
+			}
+			steps = append(steps, fieldStep(name))
+			s = rest
+		case s[0] == '[':
+			end := matchingBracket(s)
+			if end < 0 {
+				return nil, fmt.Errorf("jsonpath: unclosed '[' in %q", expr)
+			}
+			step, err := parseBracket(strings.TrimSpace(s[1:end]))
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("jsonpath: unexpected %q in %q", s, expr)
+		}
+	}
+	return steps, nil
+}
+
+func fieldStep(name string) jpStep {
+	if name == "*" {
+		return jpStep{kind: jpWildcard}
+	}
+	return jpStep{kind: jpField, name: name}
+}
+
+func readName(s string) (string, string) {
+	i := 0
+	for i < len(s) && s[i] != '.' && s[i] != '[' {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func matchingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseBracket(inner string) (jpStep, error) {
+	switch {
+	case inner == "*":
+		return jpStep{kind: jpWildcard}, nil
+	case strings.HasPrefix(inner, "?(") && strings.HasSuffix(inner, ")"):
+		cond, err := parseCondition(strings.TrimSpace(inner[2 : len(inner)-1]))
+		if err != nil {
+			return jpStep{}, err
+		}
+		return jpStep{kind: jpFilter, filter: cond}, nil
+	case strings.HasPrefix(inner, "'") || strings.HasPrefix(inner, `"`):
+		name, err := unquote(inner)
+		if err != nil {
+			return jpStep{}, fmt.Errorf("jsonpath: invalid field name %s", inner)
+		}
+		return jpStep{kind: jpField, name: name}, nil
+	case strings.Contains(inner, ":"):
+		parts := strings.SplitN(inner, ":", 2)
+		step := jpStep{kind: jpSlice}
+		for i, p := range parts {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				continue
+			}
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				return jpStep{}, fmt.Errorf("jsonpath: invalid slice [%s]", inner)
+			}
+			if i == 0 {
+				step.start = &n
+			} else {
+				step.end = &n
+			}
+		}
+		return step, nil
+	default:
+		n, err := strconv.Atoi(inner)
+		if err != nil {
+			return jpStep{}, fmt.Errorf("jsonpath: invalid index [%s]", inner)
+		}
+		return jpStep{kind: jpIndex, index: n}, nil
+	}
+}
+
+var jpOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func parseCondition(s string) (*jpCondition, error) {
+	for _, op := range jpOperators {
+		if i := strings.Index(s, op); i >= 0 {
+			left, err := parseJSONPathExpr(strings.TrimSpace(s[:i]))
+			if err != nil {
+				return nil, err
+			}
+			right, err := parseLiteral(strings.TrimSpace(s[i+len(op):]))
+			if err != nil {
+				return nil, err
+			}
+			return &jpCondition{left: left, op: op, right: right}, nil
+		}
+	}
+	// No operator: the path must exist.
+	left, err := parseJSONPathExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	return &jpCondition{left: left}, nil
+}
+
+func parseLiteral(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		return unquote(s)
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case s == "null":
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpath: invalid literal %q", s)
+	}
+	return f, nil
+}
+
+func evalJSONPath(steps []jpStep, root, current interface{}) ([]interface{}, error) {
+	values := []interface{}{current}
+	atRoot := true
+	for _, st := range steps {
+		var next []interface{}
+		switch st.kind {
+		case jpRoot:
+			next = []interface{}{root}
+		case jpField:
+			for _, v := range values {
+				switch val := v.(type) {
+				case map[string]interface{}:
+					if f, ok := val[st.name]; ok {
+						next = append(next, f)
+					}
+				case []interface{}:
+					if atRoot && st.name == "items" {
+						next = append(next, val)
+					}
+				}
+			}
+		case jpWildcard:
+			for _, v := range values {
+				next = append(next, children(v)...)
+			}
+		case jpIndex:
+			for _, v := range values {
+				list, ok := v.([]interface{})
+				if !ok {
+					continue
+				}
+				i := st.index
+				if i < 0 {
+					i += len(list)
+				}
+				if i < 0 || i >= len(list) {
+					return nil, fmt.Errorf("jsonpath: index [%d] out of range", st.index)
+				}
+				next = append(next, list[i])
+			}
+		case jpSlice:
+			for _, v := range values {
+				list, ok := v.([]interface{})
+				if !ok {
+					continue
+				}
+				start, end := 0, len(list)
+				if st.start != nil {
+					start = clampIndex(*st.start, len(list))
+				}
+				if st.end != nil {
+					end = clampIndex(*st.end, len(list))
+				}
+				if start < end {
+					next = append(next, list[start:end]...)
+				}
+			}
+		case jpRecursive:
+			for _, v := range values {
+				next = append(next, descendants(v)...)
+			}
+		case jpFilter:
+			for _, v := range values {
+				candidates := children(v)
+				if _, ok := v.(map[string]interface{}); ok {
+					candidates = []interface{}{v}
+				}
+				for _, c := range candidates {
+					ok, err := st.filter.match(root, c)
+					if err != nil {
+						return nil, err
+					}
+					if ok {
+						next = append(next, c)
+					}
+				}
+			}
+		}
+		values = next
+		atRoot = st.kind == jpRoot
+	}
+	return values, nil
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+// children returns the elements of a list or the values of a map in key
+// order.
+func children(v interface{}) []interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		return val
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]interface{}, len(keys))
+		for i, k := range keys {
+			out[i] = val[k]
+		}
+		return out
+	}
+	return nil
+}
+
+// descendants returns v and everything nested in it, depth first.
+func descendants(v interface{}) []interface{} {
+	out := []interface{}{v}
+	for _, c := range children(v) {
+		out = append(out, descendants(c)...)
+	}
+	return out
+}
+
+func (c *jpCondition) match(root, v interface{}) (bool, error) {
+	values, err := evalJSONPath(c.left, root, v)
+	if err != nil {
+		return false, err
+	}
+	if c.op == "" {
+		return len(values) > 0, nil
+	}
+	for _, left := range values {
+		if compare(left, c.op, c.right) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func compare(left interface{}, op string, right interface{}) bool {
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		switch op {
+		case "==":
+			return lf == rf
+		case "!=":
+			return lf != rf
+		case "<":
+			return lf < rf
+		case "<=":
+			return lf <= rf
+		case ">":
+			return lf > rf
+		case ">=":
+			return lf >= rf
+		}
+		return false
+	}
+	ls, rs := fmt.Sprint(left), fmt.Sprint(right)
+	switch op {
+	case "==":
+		return left == right || (isString(left) && isString(right) && ls == rs)
+	case "!=":
+		return !(left == right || (isString(left) && isString(right) && ls == rs))
+	case "<":
+		return ls < rs
+	case "<=":
+		return ls <= rs
+	case ">":
+		return ls > rs
+	case ">=":
+		return ls >= rs
+	}
+	return false
+}
+
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
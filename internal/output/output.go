@@ -0,0 +1,269 @@
+// Package output renders command results in the format selected with the
+// global -o/--output flag.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported formats.
+const (
+	Table = "table"
+	Wide  = "wide"
+	JSON  = "json"
+	YAML  = "yaml"
+	CSV   = "csv"
+)
+
+// Template formats take an argument, as in -o jsonpath='{.items[*].uid}'.
+// The -file variants read the template from a file.
+const (
+	GoTemplate     = "go-template"
+	GoTemplateFile = "go-template-file"
+	JSONPathFormat = "jsonpath"
+	JSONPathFile   = "jsonpath-file"
+)
+
+// Formats lists the accepted values of --output.
+var Formats = []string{Table, Wide, JSON, YAML, CSV}
+
+// TemplateFormats lists the formats that take a template argument.
+var TemplateFormats = []string{GoTemplate, GoTemplateFile, JSONPathFormat, JSONPathFile}
+
+// Column is a table column. Wide columns are only shown with -o wide and in
+// CSV output.
+type Column struct {
+	Header string
+	Wide   bool
+}
+
+// TableData is the tabular view of a result, used by the table, wide and csv
+// formats.
+type TableData struct {
+	Columns []Column
+	Rows    [][]string
+}
+
+// AddRow appends a row; values must line up with Columns.
+func (t *TableData) AddRow(values ...string) {
+	t.Rows = append(t.Rows, values)
+}
+
+// Printer writes results in a single format.
+type Printer struct {
+	Format string
+	Out    io.Writer
+
+	// Columns selects and orders table columns by header, ignoring case,
+	// spaces, dashes and underscores. Wide columns can be selected too.
+	Columns []string
+	// SortBy sorts table rows by a column; a leading "-" reverses the order.
+	SortBy string
+	// NoHeaders omits the header row of table and csv output.
+	NoHeaders bool
+
+	tmpl     *template.Template
+	jsonPath *JSONPath
+}
+
+// New returns a printer for format, which must be one of Formats or
+// NAME=TEMPLATE with NAME one of TemplateFormats.
+func New(format string, out io.Writer) (*Printer, error) {
+	for _, f := range Formats {
+		if f == format {
+			return &Printer{Format: format, Out: out}, nil
+		}
+	}
+	if name, arg, ok := strings.Cut(format, "="); ok {
+		return newTemplatePrinter(name, arg, out)
+	}
+	for _, f := range TemplateFormats {
+		if f == format {
+			return nil, fmt.Errorf("output format %s requires a template, e.g. -o %s=...", format, format)
+		}
+	}
+	return nil, fmt.Errorf("unknown output format %q (expected one of: %s, or %s=TEMPLATE)",
+		format, strings.Join(Formats, ", "), strings.Join(TemplateFormats, "|"))
+}
+
+func newTemplatePrinter(name, arg string, out io.Writer) (*Printer, error) {
+	text := arg
+	switch name {
+	case GoTemplateFile, JSONPathFile:
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		text = string(data)
+	case GoTemplate, JSONPathFormat:
+	default:
+		return nil, fmt.Errorf("unknown output format %q (expected one of: %s)", name, strings.Join(TemplateFormats, ", "))
+	}
+
+	p := &Printer{Format: name, Out: out}
+	switch name {
+	case GoTemplate, GoTemplateFile:
+		tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid go-template: %w", err)
+		}
+		p.tmpl = tmpl
+	default:
+		jp, err := ParseJSONPath(text)
+		if err != nil {
+			return nil, err
+		}
+		p.jsonPath = jp
+	}
+	return p, nil
+}
+
+// templateFuncs are available to go-template output in addition to the
+// text/template builtins.
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": func(sep string, v []interface{}) string {
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(parts, sep)
+	},
+}
+
+// Print renders a result. data is what json and yaml print: any value that
+// encodes to JSON, or raw JSON as []byte/json.RawMessage. t is what table,
+// wide and csv print; commands without a tabular view pass nil.
+func (p *Printer) Print(data interface{}, t *TableData) error {
+	switch p.Format {
+	case JSON:
+		return p.printJSON(data)
+	case YAML:
+		return p.printYAML(data)
+	case GoTemplate, GoTemplateFile, JSONPathFormat, JSONPathFile:
+		return p.printTemplate(data)
+	case Table, Wide, CSV:
+		if t == nil {
+			return fmt.Errorf("output format %q is not supported by this command; use json or yaml", p.Format)
+		}
+		headers, rows, err := p.layout(t, p.Format != Table)
+		if err != nil {
+			return err
+		}
+		if p.Format == CSV {
+			return p.printCSV(headers, rows)
+		}
+		return p.printTable(headers, rows)
+	}
+	return fmt.Errorf("unknown output format %q", p.Format)
+}
+
+func (p *Printer) printJSON(data interface{}) error {
+	if raw, ok := rawJSON(data); ok {
+		// Indent instead of re-encoding to keep the API's key order.
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, raw, "", "  "); err != nil {
+			return fmt.Errorf("failed to format JSON: %w", err)
+		}
+		buf.WriteByte('\n')
+		_, err := p.Out.Write(buf.Bytes())
+		return err
+	}
+	enc := json.NewEncoder(p.Out)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(data)
+}
+
+func (p *Printer) printYAML(data interface{}) error {
+	generic, err := toGeneric(data)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(p.Out)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// printTemplate applies the template to the decoded response, so fields are
+// addressed by their JSON names.
+func (p *Printer) printTemplate(data interface{}) error {
+	generic, err := toGeneric(data)
+	if err != nil {
+		return err
+	}
+	if p.tmpl != nil {
+		if err := p.tmpl.Execute(p.Out, generic); err != nil {
+			return fmt.Errorf("failed to execute go-template: %w", err)
+		}
+		return nil
+	}
+	return p.jsonPath.Execute(p.Out, generic)
+}
+
+func rawJSON(data interface{}) ([]byte, bool) {
+	switch v := data.(type) {
+	case []byte:
+		return v, true
+	case json.RawMessage:
+		return v, true
+	}
+	return nil, false
+}
+
+// toGeneric converts data to plain maps, slices and scalars via JSON, so
+// YAML output uses the same keys as JSON output.
+func toGeneric(data interface{}) (interface{}, error) {
+	raw, ok := rawJSON(data)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(data); err != nil {
+			return nil, err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return convertNumbers(generic), nil
+}
+
+// convertNumbers turns json.Number into int64 or float64, keeping integers
+// such as millisecond timestamps out of exponent notation.
+func convertNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, v2 := range val {
+			val[k] = convertNumbers(v2)
+		}
+	case []interface{}:
+		for i, v2 := range val {
+			val[i] = convertNumbers(v2)
+		}
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	}
+	return v
+}
@@ -0,0 +1,170 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrinterFormats(t *testing.T) {
+	raw := []byte(`[{"uid":"abc","title":"CPU","created":1700000000000},{"uid":"defgh","title":"Memory usage","created":1}]`)
+	table := &TableData{Columns: []Column{
+		{Header: "UID"},
+		{Header: "Title"},
+		{Header: "Created", Wide: true},
+	}}
+	table.AddRow("abc", "CPU", "1700000000000")
+	table.AddRow("defgh", "Memory usage", "1")
+
+	tests := []struct {
+		format string
+		want   []string
+		reject []string
+	}{
+		{Table, []string{"UID     Title", "defgh   Memory usage"}, []string{"Created"}},
+		{Wide, []string{"Created", "1700000000000"}, nil},
+		{JSON, []string{`"uid": "abc"`, `"created": 1700000000000`}, []string{"Status:"}},
+		{YAML, []string{"uid: abc", "- created: 1700000000000"}, []string{"e+12"}},
+		{CSV, []string{"UID,Title,Created\n", "defgh,Memory usage,1\n"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			p, err := New(tt.format, &buf)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			if err := p.Print(raw, table); err != nil {
+				t.Fatalf("Print failed: %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(buf.String(), w) {
+					t.Errorf("expected %q in output, got:\n%s", w, buf.String())
+				}
+			}
+			for _, r := range tt.reject {
+				if strings.Contains(buf.String(), r) {
+					t.Errorf("did not expect %q in output, got:\n%s", r, buf.String())
+				}
+			}
+		})
+	}
+
+	if _, err := New("xml", &bytes.Buffer{}); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+	p, _ := New(Table, &bytes.Buffer{})
+	if err := p.Print(raw, nil); err == nil {
+		t.Errorf("expected error for table output without a table")
+	}
+}
+
+func TestTemplateFormats(t *testing.T) {
+	list := []byte(`[{"uid":"abc","title":"CPU","type":"prometheus","tags":["a","b"],"created":1700000000000},{"uid":"defgh","title":"Memory usage","type":"loki","tags":[]}]`)
+	object := []byte(`{"dashboard":{"title":"CPU","panels":[{"id":1,"title":"Load"},{"id":2,"title":"Idle","targets":[{"uid":"p1"}]}]},"meta":{"folderUid":"f1"}}`)
+
+	tests := []struct {
+		format string
+		data   []byte
+		want   string
+	}{
+		{`go-template={{range .}}{{.uid}}{{"\n"}}{{end}}`, list, "abc\ndefgh\n"},
+		{`go-template={{range .}}{{.uid}}={{join "," .tags}};{{end}}`, list, "abc=a,b;defgh=;"},
+		{`go-template={{(index . 0).created}}`, list, "1700000000000"},
+		{`jsonpath={.items[*].title}`, list, "CPU Memory usage"},
+		{`jsonpath={$[0].uid}`, list, "abc"},
+		{`jsonpath={.items[-1].uid}`, list, "defgh"},
+		{`jsonpath={.items[0:1].uid}`, list, "abc"},
+		{`jsonpath={range .items[*]}{.uid}{"\t"}{.title}{"\n"}{end}`, list, "abc\tCPU\ndefgh\tMemory usage\n"},
+		{`jsonpath={.items[?(@.type=="loki")].uid}`, list, "defgh"},
+		{`jsonpath={.items[?(@.created>5)].uid}`, list, "abc"},
+		{`jsonpath={.items[0].tags}`, list, `["a","b"]`},
+		{`jsonpath=Folder {.meta.folderUid}: {.dashboard.title}`, object, "Folder f1: CPU"},
+		{`jsonpath={.dashboard.panels[*].title}`, object, "Load Idle"},
+		{`jsonpath={..uid}`, object, "p1"},
+		{`jsonpath={.meta['folderUid']}`, object, "f1"},
+		{`jsonpath=.dashboard.title`, object, "CPU"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			p, err := New(tt.format, &buf)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			if err := p.Print(tt.data, nil); err != nil {
+				t.Fatalf("Print failed: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+
+	for _, bad := range []string{"jsonpath", "jsonpath={.items", "jsonpath={range .items}", "go-template={{.uid", "mustache={{uid}}"} {
+		if _, err := New(bad, &bytes.Buffer{}); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+
+	dir := t.TempDir()
+	path := dir + "/uids.tmpl"
+	if err := os.WriteFile(path, []byte(`{{range .}}{{.uid}} {{end}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	p, err := New("go-template-file="+path, &buf)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := p.Print(list, nil); err != nil || buf.String() != "abc defgh " {
+		t.Errorf("go-template-file: got %q, %v", buf.String(), err)
+	}
+}
+
+func TestTableOptions(t *testing.T) {
+	table := &TableData{Columns: []Column{
+		{Header: "ID"},
+		{Header: "Name"},
+		{Header: "Folder UID", Wide: true},
+	}}
+	table.AddRow("10", "Zébra", "f1")
+	table.AddRow("9", "alpha\tpanel", "f2")
+	table.AddRow("100", "beta", "")
+
+	tests := []struct {
+		name    string
+		format  string
+		printer Printer
+		want    string
+	}{
+		{"auto widths", Table, Printer{}, "ID    Name\n10    Zébra\n9     alpha panel\n100   beta\n"},
+		{"sort numeric", Table, Printer{SortBy: "id", NoHeaders: true}, "9     alpha panel\n10    Zébra\n100   beta\n"},
+		{"sort descending", Table, Printer{SortBy: "-name", NoHeaders: true}, "10    Zébra\n100   beta\n9     alpha panel\n"},
+		{"columns", Table, Printer{Columns: []string{"folder-uid", "ID"}}, "Folder UID   ID\nf1           10\nf2           9\n             100\n"},
+		{"csv", CSV, Printer{Columns: []string{"name"}, SortBy: "Name", NoHeaders: true}, "alpha\tpanel\nbeta\nZébra\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p, err := New(tt.format, &buf)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			p.Columns, p.SortBy, p.NoHeaders = tt.printer.Columns, tt.printer.SortBy, tt.printer.NoHeaders
+			if err := p.Print(nil, table); err != nil {
+				t.Fatalf("Print failed: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.want, buf.String())
+			}
+		})
+	}
+
+	p, _ := New(Table, &bytes.Buffer{})
+	p.Columns = []string{"title"}
+	if err := p.Print(nil, table); err == nil || !strings.Contains(err.Error(), "available: ID, Name, Folder UID") {
+		t.Errorf("expected unknown column error, got %v", err)
+	}
+}
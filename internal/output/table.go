@@ -0,0 +1,161 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// columnPadding is the number of spaces between table columns.
+const columnPadding = 3
+
+// layout applies column selection and sorting, returning the headers and
+// cells to print. all includes wide columns when no columns are selected.
+func (p *Printer) layout(t *TableData, all bool) ([]string, [][]string, error) {
+	idx, err := p.selectColumns(t, all)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([][]string, len(t.Rows))
+	copy(rows, t.Rows)
+	if p.SortBy != "" {
+		key, desc := p.SortBy, false
+		if strings.HasPrefix(key, "-") {
+			key, desc = key[1:], true
+		}
+		col, err := columnIndex(t, key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--sort-by: %w", err)
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := cell(rows[i], col), cell(rows[j], col)
+			if desc {
+				a, b = b, a
+			}
+			return lessCell(a, b)
+		})
+	}
+
+	headers := make([]string, len(idx))
+	for i, c := range idx {
+		headers[i] = t.Columns[c].Header
+	}
+	out := make([][]string, len(rows))
+	for r, row := range rows {
+		out[r] = make([]string, len(idx))
+		for i, c := range idx {
+			out[r][i] = cell(row, c)
+		}
+	}
+	return headers, out, nil
+}
+
+func (p *Printer) selectColumns(t *TableData, all bool) ([]int, error) {
+	var idx []int
+	if len(p.Columns) > 0 {
+		for _, name := range p.Columns {
+			c, err := columnIndex(t, name)
+			if err != nil {
+				return nil, fmt.Errorf("--columns: %w", err)
+			}
+			idx = append(idx, c)
+		}
+		return idx, nil
+	}
+	for i, c := range t.Columns {
+		if all || !c.Wide {
+			idx = append(idx, i)
+		}
+	}
+	return idx, nil
+}
+
+func columnIndex(t *TableData, name string) (int, error) {
+	key := columnKey(name)
+	headers := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		if columnKey(c.Header) == key {
+			return i, nil
+		}
+		headers[i] = c.Header
+	}
+	return 0, fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(headers, ", "))
+}
+
+func columnKey(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+func cell(row []string, c int) string {
+	if c < len(row) {
+		return row[c]
+	}
+	return ""
+}
+
+// lessCell compares numerically when both cells are numbers and
+// case-insensitively otherwise.
+func lessCell(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return fa < fb
+	}
+	return strings.ToLower(a) < strings.ToLower(b)
+}
+
+// printTable writes left-aligned columns sized to their widest cell.
+func (p *Printer) printTable(headers []string, rows [][]string) error {
+	lines := rows
+	if !p.NoHeaders {
+		lines = append([][]string{headers}, rows...)
+	}
+
+	widths := make([]int, len(headers))
+	for _, line := range lines {
+		for i, v := range line {
+			line[i] = sanitizeCell(v)
+			if n := utf8.RuneCountInString(line[i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.Reset()
+		for i, v := range line {
+			b.WriteString(v)
+			if i < len(line)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)+columnPadding))
+			}
+		}
+		b.WriteByte('\n')
+		if _, err := io.WriteString(p.Out, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sanitizeCell keeps a cell on one line so it cannot break the alignment.
+func sanitizeCell(v string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(v)
+}
+
+func (p *Printer) printCSV(headers []string, rows [][]string) error {
+	w := csv.NewWriter(p.Out)
+	if !p.NoHeaders {
+		w.Write(headers)
+	}
+	for _, row := range rows {
+		w.Write(row)
+	}
+	w.Flush()
+	return w.Error()
+}
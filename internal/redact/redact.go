@@ -0,0 +1,35 @@
+// Package redact masks secrets before they are printed, in request traces as
+// well as in profiles shown by the config commands.
+package redact
+
+import "strings"
+
+// Mask replaces secret values.
+const Mask = "********"
+
+// IsSensitiveHeader reports whether an HTTP header carries credentials, by
+// its name.
+func IsSensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "authorization", "proxy-authorization", "cookie", "set-cookie":
+		return true
+	}
+	for _, s := range []string{"secret", "token", "password", "api-key", "apikey"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// HeaderValue masks the value of a sensitive header, keeping the auth scheme
+// of Authorization headers.
+func HeaderValue(name, v string) string {
+	if strings.EqualFold(name, "Authorization") || strings.EqualFold(name, "Proxy-Authorization") {
+		if scheme, _, ok := strings.Cut(v, " "); ok {
+			return scheme + " " + Mask
+		}
+	}
+	return Mask
+}